@@ -0,0 +1,180 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// anthropicVersion is the version of the anthropic messages api
+const anthropicVersion = "2023-06-01"
+
+// anthropicMaxTokens is the default token limit, as the anthropic api requires one
+const anthropicMaxTokens = 4096
+
+// Anthropic API conform request
+type AnthropicRequest struct {
+	Model string `json:"model"`
+	// System is a string, or a list of text blocks if a system message is cached
+	System    any                `json:"system,omitempty"`
+	Messages  []AnthropicMessage `json:"messages"`
+	MaxTokens int                `json:"max_tokens"`
+}
+
+// Anthropic API conform message
+type AnthropicMessage struct {
+	Role    string             `json:"role"`
+	Content []AnthropicContent `json:"content"`
+}
+
+// Anthropic API conform content block
+type AnthropicContent struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text,omitempty"`
+	Source       *AnthropicImageSource  `json:"source,omitempty"`
+	CacheControl *AnthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// Anthropic API conform cache breakpoint
+type AnthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+// Anthropic API conform image source
+type AnthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      []byte `json:"data"`
+}
+
+// Anthropic API conform response
+type AnthropicResponse struct {
+	ID      string             `json:"id"`
+	Model   string             `json:"model"`
+	Content []AnthropicContent `json:"content"`
+	Usage   struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// isAnthropic checks if the url points to the anthropic messages api
+func (c *Client) isAnthropic() bool {
+	return strings.HasSuffix(strings.TrimSuffix(c.URL, "/"), "/v1/messages")
+}
+
+// NewAnthropicRequest converts messages to the anthropic format. System messages are
+// moved to the system field, as the anthropic api doesn't allow them in the messages.
+// Cached messages get a cache breakpoint on their last block.
+func NewAnthropicRequest(model string, messages []Message) AnthropicRequest {
+	req := AnthropicRequest{
+		Model:     model,
+		MaxTokens: anthropicMaxTokens,
+	}
+
+	var system []string
+	var systemBlocks []AnthropicContent
+	systemCached := false
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			system = append(system, msg.Content)
+			systemBlocks = append(systemBlocks, AnthropicContent{Type: "text", Text: msg.Content, CacheControl: cacheControl(msg)})
+			systemCached = systemCached || msg.Cache
+			continue
+		}
+
+		var content []AnthropicContent
+		for _, img := range msg.Images {
+			content = append(content, AnthropicContent{
+				Type: "image",
+				Source: &AnthropicImageSource{
+					Type:      "base64",
+					MediaType: img.MediaType,
+					Data:      img.Data,
+				},
+			})
+		}
+		if msg.Content != "" || len(content) == 0 {
+			content = append(content, AnthropicContent{Type: "text", Text: msg.Content})
+		}
+		content[len(content)-1].CacheControl = cacheControl(msg)
+
+		req.Messages = append(req.Messages, AnthropicMessage{Role: msg.Role, Content: content})
+	}
+
+	if systemCached {
+		req.System = systemBlocks
+	} else if len(system) > 0 {
+		req.System = strings.Join(system, "\n\n")
+	}
+
+	return req
+}
+
+// cacheControl returns the cache breakpoint of a cached message, else nil
+func cacheControl(msg Message) *AnthropicCacheControl {
+	if !msg.Cache {
+		return nil
+	}
+	return &AnthropicCacheControl{Type: "ephemeral"}
+}
+
+// Text returns the text of all text blocks of the response
+func (r AnthropicResponse) Text() string {
+	var text strings.Builder
+	for _, content := range r.Content {
+		if content.Type == "text" {
+			text.WriteString(content.Text)
+		}
+	}
+	return text.String()
+}
+
+func (c *Client) completeAnthropic(messages []Message) (*Completion, error) {
+	data, err := json.Marshal(NewAnthropicRequest(c.Model, messages))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest("POST", c.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", c.APIKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var res AnthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	if len(res.Content) == 0 {
+		return nil, errors.New("no response")
+	}
+
+	return &Completion{
+		Content: res.Text(),
+		Model:   res.Model,
+		Usage: Usage{
+			PromptTokens:     res.Usage.InputTokens,
+			CompletionTokens: res.Usage.OutputTokens,
+			TotalTokens:      res.Usage.InputTokens + res.Usage.OutputTokens,
+		},
+	}, nil
+}
@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Cassette modes
+const (
+	// CassetteAuto replays recorded responses and records missing ones
+	CassetteAuto = "auto"
+	// CassetteRecord always sends the requests and records the responses
+	CassetteRecord = "record"
+	// CassetteReplay only replays recorded responses and fails for unknown requests
+	CassetteReplay = "replay"
+)
+
+// Cassette records completions in a file and replays them for identical requests, so
+// workflows can be tested without access to the api.
+type Cassette struct {
+	File string
+	Mode string
+
+	mu           sync.Mutex
+	interactions map[string]Interaction
+}
+
+// Interaction is a recorded request and its completion
+type Interaction struct {
+	Key        string     `json:"key"`
+	Model      string     `json:"model"`
+	Messages   []Message  `json:"messages"`
+	Completion Completion `json:"completion"`
+}
+
+// LoadCassette loads the cassette from the file. A missing file results in an empty
+// cassette, except in replay mode.
+func LoadCassette(file string, mode string) (*Cassette, error) {
+	switch mode {
+	case "":
+		mode = CassetteAuto
+	case CassetteAuto, CassetteRecord, CassetteReplay:
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q, expected auto, record or replay", mode)
+	}
+
+	c := &Cassette{File: file, Mode: mode, interactions: map[string]Interaction{}}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) && mode != CassetteReplay {
+			return c, nil
+		}
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+
+	var interactions []Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("error parsing cassette %s: %w", file, err)
+	}
+	for _, interaction := range interactions {
+		c.interactions[interaction.Key] = interaction
+	}
+
+	return c, nil
+}
+
+// CassetteKey returns the key identifying a request in a cassette
+func CassetteKey(model string, messages []Message) string {
+	data, _ := json.Marshal(struct {
+		Model    string    `json:"model"`
+		Messages []Message `json:"messages"`
+	}{model, messages})
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// play returns the recorded completion of the request, or sends it with complete and
+// records the completion, depending on the mode.
+func (c *Cassette) play(model string, messages []Message, complete func([]Message) (*Completion, error)) (*Completion, error) {
+	key := CassetteKey(model, messages)
+
+	if c.Mode != CassetteRecord {
+		c.mu.Lock()
+		interaction, ok := c.interactions[key]
+		c.mu.Unlock()
+
+		if ok {
+			completion := interaction.Completion
+			return &completion, nil
+		}
+		if c.Mode == CassetteReplay {
+			return nil, fmt.Errorf("request not found in cassette %s (key %s), record it with cassette mode auto or record", c.File, key[:12])
+		}
+	}
+
+	completion, err := complete(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interactions[key] = Interaction{Key: key, Model: model, Messages: messages, Completion: *completion}
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+
+	return completion, nil
+}
+
+// save writes all interactions sorted by key, so the file only changes for new requests
+func (c *Cassette) save() error {
+	interactions := make([]Interaction, 0, len(c.interactions))
+	for _, interaction := range c.interactions {
+		interactions = append(interactions, interaction)
+	}
+	sort.Slice(interactions, func(i, j int) bool {
+		return interactions[i].Key < interactions[j].Key
+	})
+
+	data, err := json.MarshalIndent(interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cassette: %w", err)
+	}
+
+	if dir := filepath.Dir(c.File); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("error creating cassette directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(c.File, data, 0644); err != nil {
+		return fmt.Errorf("error writing cassette: %w", err)
+	}
+	return nil
+}
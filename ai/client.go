@@ -15,6 +15,24 @@ type Client struct {
 	APIKey string
 	Model  string
 
+	// Profile is the name of the config profile the client was created from. It is
+	// recorded with the runs, so they can be sent again with the same settings.
+	Profile string
+
+	EmbeddingURL   string
+	EmbeddingModel string
+
+	// Cassette records and replays completions if set
+	Cassette *Cassette
+
+	// Pool sends the completions to its endpoints instead of the url if set
+	Pool *Pool
+
+	// Params are additional fields of the requests to OpenAI compatible apis, like
+	// max_tokens. They replace the fields clai sets, except the model, the messages
+	// and stream.
+	Params map[string]json.RawMessage
+
 	client *http.Client
 }
 
@@ -34,20 +52,59 @@ func NewClient(opts ...Options) *Client {
 	return c
 }
 
+// Do sends the messages and returns the response text
 func (c *Client) Do(messages []Message) (string, error) {
+	completion, err := c.Complete(messages)
+	if err != nil {
+		return "", err
+	}
+	return completion.Content, nil
+}
+
+// SetModel sets the model the completions are sent to. A pool sends them to its
+// endpoints with their own models, so another model is an error for a client with a
+// pool.
+func (c *Client) SetModel(model string) error {
+	if c.Pool != nil && model != c.Model {
+		return fmt.Errorf("model %s can't be used with an endpoint pool, its endpoints use their own models", model)
+	}
+	c.Model = model
+	return nil
+}
+
+// Complete sends the messages and returns the response including the token usage
+func (c *Client) Complete(messages []Message) (*Completion, error) {
+	if c.Cassette != nil {
+		return c.Cassette.play(c.Model, messages, c.complete)
+	}
+	return c.complete(messages)
+}
+
+func (c *Client) complete(messages []Message) (*Completion, error) {
+	if c.Pool != nil {
+		return c.Pool.complete(messages, c.Params)
+	}
+	if c.isMock() {
+		return c.completeMock(messages)
+	}
+	if c.isAnthropic() {
+		return c.completeAnthropic(messages)
+	}
+
 	req := Request{
 		Model:    c.Model,
-		Messages: messages,
+		Messages: NewRequestMessages(messages),
+		Params:   c.Params,
 	}
 
 	data, err := json.Marshal(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	httpReq, err := http.NewRequest("POST", c.URL, bytes.NewReader(data))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -55,23 +112,32 @@ func (c *Client) Do(messages []Message) (string, error) {
 
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
-		return "", errors.New(string(body))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
+	return decodeResponse(resp.Body)
+}
+
+// decodeResponse decodes an OpenAI API conform response
+func decodeResponse(body io.Reader) (*Completion, error) {
 	var res Response
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		return "", err
+	if err := json.NewDecoder(body).Decode(&res); err != nil {
+		return nil, err
 	}
 
 	if len(res.Choices) == 0 {
-		return "", errors.New("no response")
+		return nil, errors.New("no response")
 	}
 
-	return res.Choices[0].Message.Content, nil
+	return &Completion{
+		Content: res.Choices[0].Message.Content,
+		Model:   res.Model,
+		Usage:   res.Usage.Usage,
+	}, nil
 }
@@ -0,0 +1,195 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMessages = []Message{
+	{Role: "system", Content: "You are a cartographer."},
+	{Role: "user", Content: "Describe this map.", Images: []Image{{Name: "cave.png", MediaType: "image/png", Data: []byte("png")}}},
+}
+
+func TestDoOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		messages := req["messages"].([]any)
+		assert.Equal(t, "You are a cartographer.", messages[0].(map[string]any)["content"])
+		assert.Equal(t, []any{
+			map[string]any{"type": "text", "text": "Describe this map."},
+			map[string]any{"type": "image_url", "image_url": map[string]any{"url": "data:image/png;base64,cG5n"}},
+		}, messages[1].(map[string]any)["content"])
+
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"A cave."}}]}`))
+	}))
+	defer server.Close()
+
+	res, err := NewClient(WithURL(server.URL + "/v1/chat/completions")).Do(testMessages)
+	require.NoError(t, err)
+	assert.Equal(t, "A cave.", res)
+}
+
+func TestDoAnthropic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key", r.Header.Get("x-api-key"))
+		assert.Equal(t, anthropicVersion, r.Header.Get("anthropic-version"))
+
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		assert.Equal(t, "You are a cartographer.", req["system"])
+		assert.Equal(t, []any{
+			map[string]any{
+				"role": "user",
+				"content": []any{
+					map[string]any{"type": "image", "source": map[string]any{"type": "base64", "media_type": "image/png", "data": "cG5n"}},
+					map[string]any{"type": "text", "text": "Describe this map."},
+				},
+			},
+		}, req["messages"])
+
+		w.Write([]byte(`{"content":[{"type":"text","text":"A cave."}]}`))
+	}))
+	defer server.Close()
+
+	res, err := NewClient(WithURL(server.URL+"/v1/messages"), WithAPIKey("key")).Do(testMessages)
+	require.NoError(t, err)
+	assert.Equal(t, "A cave.", res)
+}
+
+func TestMessageAttributes(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "Rules", Cache: true},
+		{Role: "system", Content: "Setting"},
+		{Role: "user", Content: "Hi", Name: "dm", Cache: true},
+	}
+
+	req := NewAnthropicRequest("claude", messages)
+	assert.Equal(t, []AnthropicContent{
+		{Type: "text", Text: "Rules", CacheControl: &AnthropicCacheControl{Type: "ephemeral"}},
+		{Type: "text", Text: "Setting"},
+	}, req.System)
+	assert.Equal(t, &AnthropicCacheControl{Type: "ephemeral"}, req.Messages[0].Content[0].CacheControl)
+
+	converted := NewRequestMessages(messages)
+	assert.Equal(t, "dm", converted[2].Name)
+	assert.Equal(t, "", converted[0].Name)
+}
+
+func TestDoMock(t *testing.T) {
+	res, err := NewClient(WithMock("")).Do(testMessages)
+	require.NoError(t, err)
+	assert.Equal(t, "Describe this map.", res)
+
+	script := filepath.Join(t.TempDir(), "script.yaml")
+	require.NoError(t, os.WriteFile(script, []byte(`
+- match: cartographer
+  content: A map of the cave.
+- content: Anything else.
+`), 0644))
+
+	completion, err := NewClient(WithMock(script), WithModel("mock")).Complete(testMessages)
+	require.NoError(t, err)
+	assert.Equal(t, "A map of the cave.", completion.Content)
+	assert.Equal(t, Usage{PromptTokens: 7, CompletionTokens: 5, TotalTokens: 12}, completion.Usage)
+
+	res, err = NewClient(WithMock(script)).Do([]Message{{Role: "user", Content: "Hi"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Anything else.", res)
+
+	require.NoError(t, os.WriteFile(script, []byte("- error: rate limited\n"), 0644))
+	_, err = NewClient(WithMock(script)).Do(testMessages)
+	assert.EqualError(t, err, "rate limited")
+}
+
+func TestCassette(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cassette.json")
+
+	_, err := LoadCassette(file, CassetteReplay)
+	assert.Error(t, err)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"A cave."}}],"usage":{"total_tokens":3}}`))
+	}))
+	defer server.Close()
+
+	cassette, err := LoadCassette(file, CassetteAuto)
+	require.NoError(t, err)
+
+	client := NewClient(WithURL(server.URL), WithModel("a"), WithCassette(cassette))
+	for i := 0; i < 2; i++ {
+		completion, err := client.Complete(testMessages)
+		require.NoError(t, err)
+		assert.Equal(t, "A cave.", completion.Content)
+		assert.Equal(t, 3, completion.Usage.TotalTokens)
+	}
+	assert.Equal(t, 1, calls)
+
+	// A replay only cassette works without the server
+	server.Close()
+	cassette, err = LoadCassette(file, CassetteReplay)
+	require.NoError(t, err)
+
+	res, err := NewClient(WithURL(server.URL), WithModel("a"), WithCassette(cassette)).Do(testMessages)
+	require.NoError(t, err)
+	assert.Equal(t, "A cave.", res)
+
+	_, err = NewClient(WithURL(server.URL), WithModel("b"), WithCassette(cassette)).Do(testMessages)
+	assert.ErrorContains(t, err, "not found in cassette")
+
+	_, err = LoadCassette(file, "rewind")
+	assert.Error(t, err)
+}
+
+func TestParams(t *testing.T) {
+	var received []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		received = append(received, req)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"A cave."}}]}`))
+	}))
+	defer server.Close()
+
+	params := map[string]json.RawMessage{
+		"temperature": json.RawMessage(`0.7`),
+		"max_tokens":  json.RawMessage(`5`),
+		"model":       json.RawMessage(`"other"`),
+		"messages":    json.RawMessage(`[]`),
+	}
+
+	client := NewClient(WithURL(server.URL), WithModel("gpt-4o"))
+	client.Params = params
+	_, err := client.Do(testMessages[:1])
+	require.NoError(t, err)
+
+	// The endpoints of a pool get the params of the client
+	pool, err := NewPool("", &Endpoint{Name: "a", Client: NewClient(WithURL(server.URL), WithModel("gpt-4o"))})
+	require.NoError(t, err)
+	client = NewClient(WithPool(pool))
+	client.Params = params
+	_, err = client.Do(testMessages[:1])
+	require.NoError(t, err)
+	assert.Nil(t, pool.Endpoint("a").Client.Params)
+
+	require.Len(t, received, 2)
+	for _, req := range received {
+		assert.Equal(t, map[string]any{
+			"model":       "gpt-4o",
+			"temperature": 0.7,
+			"max_tokens":  5.0,
+			"messages":    []any{map[string]any{"role": "system", "content": "You are a cartographer."}},
+		}, req)
+	}
+}
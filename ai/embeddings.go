@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// EmbeddingRequest is an OpenAI and Ollama (/api/embed) API conform embedding request
+type EmbeddingRequest struct {
+	Model  string   `json:"model"`
+	Input  []string `json:"input,omitempty"`
+	Prompt string   `json:"prompt,omitempty"`
+}
+
+// EmbeddingResponse contains the fields of the OpenAI and Ollama embedding responses
+type EmbeddingResponse struct {
+	// OpenAI
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+
+	// Ollama /api/embed
+	Embeddings [][]float32 `json:"embeddings"`
+
+	// Ollama /api/embeddings (legacy)
+	Embedding []float32 `json:"embedding"`
+}
+
+// Embed returns the embedding vectors of the texts. Depending on the embedding url
+// the OpenAI (/v1/embeddings) or Ollama (/api/embed, /api/embeddings) api is used.
+func (c *Client) Embed(texts []string) ([][]float32, error) {
+	if c.EmbeddingModel == "" {
+		return nil, errors.New("no embedding model configured (set embedding_model)")
+	}
+
+	url := c.embeddingURL()
+
+	// The legacy ollama endpoint only supports a single prompt per request
+	if strings.HasSuffix(url, "/api/embeddings") {
+		vectors := make([][]float32, 0, len(texts))
+		for _, text := range texts {
+			res, err := c.doEmbedding(url, EmbeddingRequest{Model: c.EmbeddingModel, Prompt: text})
+			if err != nil {
+				return nil, err
+			}
+			vectors = append(vectors, res.Embedding)
+		}
+		return vectors, nil
+	}
+
+	res, err := c.doEmbedding(url, EmbeddingRequest{Model: c.EmbeddingModel, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := res.Embeddings
+	if vectors == nil {
+		vectors = make([][]float32, len(res.Data))
+		for _, data := range res.Data {
+			if data.Index < 0 || data.Index >= len(vectors) {
+				return nil, fmt.Errorf("invalid embedding index %d", data.Index)
+			}
+			vectors[data.Index] = data.Embedding
+		}
+	}
+
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(vectors))
+	}
+
+	return vectors, nil
+}
+
+// EmbedModel returns the embedding model of the client
+func (c *Client) EmbedModel() string {
+	return c.EmbeddingModel
+}
+
+// embeddingURL returns the embedding url. If none is set it is derived from the
+// chat completion url.
+func (c *Client) embeddingURL() string {
+	if c.EmbeddingURL != "" {
+		return c.EmbeddingURL
+	}
+	if strings.HasSuffix(c.URL, "/api/chat") {
+		return strings.TrimSuffix(c.URL, "/api/chat") + "/api/embed"
+	}
+	return strings.TrimSuffix(c.URL, "/chat/completions") + "/embeddings"
+}
+
+func (c *Client) doEmbedding(url string, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, errors.New(string(body))
+	}
+
+	var res EmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req EmbeddingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "embed-model", req.Model)
+
+		switch r.URL.Path {
+		case "/v1/embeddings":
+			assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+			// Return out of order to check that the index is respected
+			w.Write([]byte(`{"data":[{"embedding":[2,2],"index":1},{"embedding":[1,1],"index":0}]}`))
+		case "/api/embed":
+			w.Write([]byte(`{"embeddings":[[1,1],[2,2]]}`))
+		case "/api/embeddings":
+			if req.Prompt == "a" {
+				w.Write([]byte(`{"embedding":[1,1]}`))
+			} else {
+				w.Write([]byte(`{"embedding":[2,2]}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}
+	}))
+	defer server.Close()
+
+	want := [][]float32{{1, 1}, {2, 2}}
+	tests := []struct {
+		name string
+		opts []Options
+	}{
+		{name: "openai derived url", opts: []Options{WithURL(server.URL + "/v1/chat/completions")}},
+		{name: "ollama derived url", opts: []Options{WithURL(server.URL + "/api/chat")}},
+		{name: "ollama legacy", opts: []Options{WithEmbeddingURL(server.URL + "/api/embeddings")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(append(tt.opts, WithAPIKey("key"), WithEmbeddingModel("embed-model"))...)
+			got, err := client.Embed([]string{"a", "b"})
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	_, err := NewClient(WithURL(server.URL + "/v1/chat/completions")).Embed([]string{"a"})
+	assert.Error(t, err)
+
+	_, err = NewClient(WithEmbeddingURL(server.URL+"/missing"), WithEmbeddingModel("embed-model")).Embed([]string{"a"})
+	assert.Error(t, err)
+}
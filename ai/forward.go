@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Forward sends an OpenAI API conform request body unchanged to the api and returns
+// the response, which the caller has to close. Only the model is set to the one of the
+// client, if it has one. Pools forward to their endpoints with their own models and
+// fail over like Complete. The anthropic and the mock provider don't accept these
+// requests, so they can't be forwarded to.
+func (c *Client) Forward(body []byte) (*http.Response, error) {
+	if c.Pool != nil {
+		return c.Pool.Forward(body)
+	}
+	if c.isMock() || c.isAnthropic() {
+		return nil, fmt.Errorf("requests can't be forwarded to %s, it isn't an OpenAI compatible api", c.URL)
+	}
+
+	if c.Model != "" {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, err
+		}
+		fields["model"], _ = json.Marshal(c.Model)
+
+		var err error
+		if body, err = json.Marshal(fields); err != nil {
+			return nil, err
+		}
+	}
+
+	httpReq, err := http.NewRequest("POST", c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+
+	return c.client.Do(httpReq)
+}
+
+// Forward sends the request body to the first available endpoint and fails over to
+// the next one on retryable errors and statuses. Other statuses are returned as
+// response.
+func (p *Pool) Forward(body []byte) (*http.Response, error) {
+	var errs []string
+	for _, endpoint := range p.order() {
+		resp, err := endpoint.Client.Forward(body)
+		if err == nil && (resp.StatusCode == 429 || resp.StatusCode >= 500) {
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			err = &StatusError{StatusCode: resp.StatusCode, Body: string(data)}
+		}
+		p.report(endpoint, err)
+
+		if err == nil {
+			return resp, nil
+		}
+		if !Retryable(err) {
+			return nil, fmt.Errorf("%s: %w", endpoint.Name, err)
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", endpoint.Name, strings.TrimSpace(err.Error())))
+	}
+
+	if len(errs) == 0 {
+		return nil, errors.New("all endpoints are unavailable (circuit open)")
+	}
+	return nil, fmt.Errorf("all endpoints failed: %s", strings.Join(errs, "; "))
+}
@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForward(t *testing.T) {
+	var received map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		received = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusTeapot)
+		fmt.Fprint(w, "teapot")
+	}))
+	defer server.Close()
+
+	body := `{"model": "gpt-4o", "max_tokens": 5, "messages": [{"role": "tool", "tool_call_id": "1", "content": "4"}]}`
+	tests := []struct {
+		model    string
+		expected string
+	}{
+		{model: "", expected: "gpt-4o"},
+		{model: "llama3", expected: "llama3"},
+	}
+
+	for _, tt := range tests {
+		resp, err := NewClient(WithURL(server.URL), WithAPIKey("key"), WithModel(tt.model)).Forward([]byte(body))
+		require.NoError(t, err, tt.model)
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// The response is returned whatever the status
+		assert.Equal(t, http.StatusTeapot, resp.StatusCode, tt.model)
+		assert.Equal(t, "teapot", string(data), tt.model)
+
+		var expected map[string]any
+		require.NoError(t, json.Unmarshal([]byte(body), &expected))
+		expected["model"] = tt.expected
+		assert.Equal(t, expected, received, tt.model)
+	}
+
+	_, err := NewClient(WithMock("")).Forward([]byte(body))
+	assert.ErrorContains(t, err, "can't be forwarded")
+}
+
+func TestPoolForward(t *testing.T) {
+	overloaded := newTestEndpoint(t, "overloaded")
+	overloaded.status.Store(http.StatusServiceUnavailable)
+	healthy := newTestEndpoint(t, "healthy")
+
+	pool, err := NewPool("", overloaded.endpoint("overloaded", 0), healthy.endpoint("healthy", 0))
+	require.NoError(t, err)
+
+	resp, err := NewClient(WithPool(pool)).Forward([]byte(`{"messages": []}`))
+	require.NoError(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, `{"choices":[{"message":{"content":"healthy"}}]}`, string(data))
+	assert.Equal(t, int32(1), overloaded.hits.Load())
+
+	// Client errors are returned as response
+	overloaded.status.Store(http.StatusBadRequest)
+	resp, err = pool.Forward([]byte(`{"messages": []}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	healthy.status.Store(http.StatusInternalServerError)
+	overloaded.status.Store(http.StatusBadGateway)
+	_, err = pool.Forward([]byte(`{"messages": []}`))
+	assert.ErrorContains(t, err, "all endpoints failed")
+}
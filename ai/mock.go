@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mockPrefix is the url scheme of the mock provider. "mock://echo" answers with the
+// content of the last message, "mock://<file>" with the scripted responses of the file.
+const mockPrefix = "mock://"
+
+// MockResponse is a scripted response of the mock provider. A response is used for a
+// request if the content of one of its messages contains Match, an empty Match
+// matches every request. If Error is set the request fails with it.
+type MockResponse struct {
+	Match   string `yaml:"match"`
+	Content string `yaml:"content"`
+	Error   string `yaml:"error"`
+}
+
+// isMock checks if the url points to the mock provider
+func (c *Client) isMock() bool {
+	return strings.HasPrefix(c.URL, mockPrefix)
+}
+
+// completeMock answers with the first scripted response that matches the messages.
+// Without a matching response the content of the last message is echoed.
+func (c *Client) completeMock(messages []Message) (*Completion, error) {
+	var responses []MockResponse
+	if script := strings.TrimPrefix(c.URL, mockPrefix); script != "echo" && script != "" {
+		data, err := os.ReadFile(script)
+		if err != nil {
+			return nil, fmt.Errorf("error reading mock script: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &responses); err != nil {
+			return nil, fmt.Errorf("error parsing mock script %s: %w", script, err)
+		}
+	}
+
+	var prompt strings.Builder
+	for _, msg := range messages {
+		prompt.WriteString(msg.Content)
+		prompt.WriteString("\n")
+	}
+
+	content := ""
+	if len(messages) > 0 {
+		content = messages[len(messages)-1].Content
+	}
+	for _, response := range responses {
+		if strings.Contains(prompt.String(), response.Match) {
+			if response.Error != "" {
+				return nil, errors.New(response.Error)
+			}
+			content = response.Content
+			break
+		}
+	}
+
+	// Token usage is approximated by counting words
+	promptTokens := len(strings.Fields(prompt.String()))
+	completionTokens := len(strings.Fields(content))
+	return &Completion{
+		Content: content,
+		Model:   c.Model,
+		Usage: Usage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		},
+	}, nil
+}
@@ -39,9 +39,56 @@ func WithOpenAI() Options {
 	}
 }
 
+// WithAnthropic sets the anthropic url
+func WithAnthropic() Options {
+	return func(c *Client) {
+		c.URL = "https://api.anthropic.com/v1/messages"
+	}
+}
+
+// WithMock sets the url of the mock provider answering with the scripted responses
+// of the file, or echoing the last message if the file is empty
+func WithMock(script string) Options {
+	return func(c *Client) {
+		if script == "" {
+			script = "echo"
+		}
+		c.URL = mockPrefix + script
+	}
+}
+
 // WithOpenRouter sets the openrouter url
 func WithOpenRouter() Options {
 	return func(c *Client) {
 		c.URL = "https://openrouter.ai/api/v1/chat/completions"
 	}
 }
+
+// WithEmbeddingURL sets the embedding api url
+func WithEmbeddingURL(url string) Options {
+	return func(c *Client) {
+		c.EmbeddingURL = url
+	}
+}
+
+// WithEmbeddingModel sets the embedding model
+func WithEmbeddingModel(model string) Options {
+	return func(c *Client) {
+		c.EmbeddingModel = model
+	}
+}
+
+// WithCassette records and replays the completions with the cassette
+func WithCassette(cassette *Cassette) Options {
+	return func(c *Client) {
+		c.Cassette = cassette
+	}
+}
+
+// WithPool sends the completions to the endpoints of the pool, with failover and
+// optional load balancing
+func WithPool(pool *Pool) Options {
+	return func(c *Client) {
+		c.Pool = pool
+	}
+}
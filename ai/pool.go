@@ -0,0 +1,206 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Balancing strategies of a pool
+const (
+	// BalanceFailover always tries the endpoints in order
+	BalanceFailover = "failover"
+	// BalanceRoundRobin distributes the requests by the weights of the endpoints and
+	// fails over to the remaining endpoints in order
+	BalanceRoundRobin = "round_robin"
+)
+
+// Endpoint is an api a pool can send completions to
+type Endpoint struct {
+	Name   string
+	Client *Client
+	// Weight is the share of requests with round robin balancing, defaults to 1
+	Weight int
+
+	failures    int
+	openUntil   time.Time
+	roundWeight int
+}
+
+// Pool sends completions to a list of endpoints. If an endpoint fails with a
+// connection error, a 5xx or a 429 status, the next endpoint is tried. Endpoints that
+// failed several times in a row are skipped for a cooldown (circuit breaker).
+type Pool struct {
+	Endpoints []*Endpoint
+	Balance   string
+
+	// Failures is the number of consecutive failures that open the circuit of an
+	// endpoint, defaults to 3
+	Failures int
+	// Cooldown is the time an open circuit skips the endpoint, defaults to 30 seconds
+	Cooldown time.Duration
+
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewPool creates a pool of the endpoints with the default circuit breaker settings
+func NewPool(balance string, endpoints ...*Endpoint) (*Pool, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("pool has no endpoints")
+	}
+
+	switch balance {
+	case "":
+		balance = BalanceFailover
+	case BalanceFailover, BalanceRoundRobin:
+	default:
+		return nil, fmt.Errorf("unknown balance %q, expected %s or %s", balance, BalanceFailover, BalanceRoundRobin)
+	}
+
+	for _, endpoint := range endpoints {
+		if endpoint.Weight < 0 {
+			return nil, fmt.Errorf("endpoint %s has a negative weight", endpoint.Name)
+		}
+		if endpoint.Weight == 0 {
+			endpoint.Weight = 1
+		}
+	}
+
+	return &Pool{Endpoints: endpoints, Balance: balance, Failures: 3, Cooldown: 30 * time.Second, now: time.Now}, nil
+}
+
+// Endpoint returns the endpoint with the name, or nil if there is none
+func (p *Pool) Endpoint(name string) *Endpoint {
+	if p == nil {
+		return nil
+	}
+	for _, endpoint := range p.Endpoints {
+		if endpoint.Name == name {
+			return endpoint
+		}
+	}
+	return nil
+}
+
+// Retryable reports if a request failing with the error should be sent to another
+// endpoint. These are rate limits, server errors, timeouts and failed or dropped
+// connections, but not errors like an invalid url or certificate.
+func Retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode == 429 || status.StatusCode >= 500
+	}
+
+	// url.Error implements net.Error too, so only timeouts are taken from it
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Complete sends the messages to the first available endpoint and fails over to the
+// next one on retryable errors
+func (p *Pool) Complete(messages []Message) (*Completion, error) {
+	return p.complete(messages, nil)
+}
+
+// complete is like Complete, but sends the params of the requests to the endpoints
+func (p *Pool) complete(messages []Message, params map[string]json.RawMessage) (*Completion, error) {
+	var errs []string
+	for _, endpoint := range p.order() {
+		client := endpoint.Client
+		if params != nil {
+			// The endpoints are shared, so the params are set on a copy
+			withParams := *client
+			withParams.Params = params
+			client = &withParams
+		}
+
+		completion, err := client.Complete(messages)
+		p.report(endpoint, err)
+
+		if err == nil {
+			completion.Endpoint = endpoint.Name
+			return completion, nil
+		}
+		if !Retryable(err) {
+			return nil, fmt.Errorf("%s: %w", endpoint.Name, err)
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", endpoint.Name, strings.TrimSpace(err.Error())))
+	}
+
+	if len(errs) == 0 {
+		return nil, errors.New("all endpoints are unavailable (circuit open)")
+	}
+	return nil, fmt.Errorf("all endpoints failed: %s", strings.Join(errs, "; "))
+}
+
+// order returns the endpoints to try for the next request, skipping open circuits
+func (p *Pool) order() []*Endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock()
+	var available []*Endpoint
+	for _, endpoint := range p.Endpoints {
+		if now.Before(endpoint.openUntil) {
+			continue
+		}
+		available = append(available, endpoint)
+	}
+
+	if p.Balance != BalanceRoundRobin || len(available) < 2 {
+		return available
+	}
+
+	// Smooth weighted round robin, the selected endpoint goes first and the others
+	// stay in order for the failover
+	total := 0
+	selected := 0
+	for i, endpoint := range available {
+		endpoint.roundWeight += endpoint.Weight
+		total += endpoint.Weight
+		if endpoint.roundWeight > available[selected].roundWeight {
+			selected = i
+		}
+	}
+	available[selected].roundWeight -= total
+
+	ordered := []*Endpoint{available[selected]}
+	ordered = append(ordered, available[:selected]...)
+	return append(ordered, available[selected+1:]...)
+}
+
+// report updates the circuit breaker of the endpoint with the result of a request
+func (p *Pool) report(endpoint *Endpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil || !Retryable(err) {
+		endpoint.failures = 0
+		return
+	}
+
+	// The failures are only reset by a success, so after the cooldown a single
+	// failure opens the circuit again
+	endpoint.failures++
+	if p.Failures > 0 && endpoint.failures >= p.Failures {
+		endpoint.openUntil = p.clock().Add(p.Cooldown)
+	}
+}
+
+func (p *Pool) clock() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now()
+}
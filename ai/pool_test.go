@@ -0,0 +1,222 @@
+package ai
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEndpoint is a local api answering with its name or failing with the status
+type testEndpoint struct {
+	server *httptest.Server
+	status atomic.Int32
+	hits   atomic.Int32
+}
+
+func newTestEndpoint(t *testing.T, name string) *testEndpoint {
+	endpoint := &testEndpoint{}
+	endpoint.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint.hits.Add(1)
+		if status := int(endpoint.status.Load()); status != 0 {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, "%s failed", name)
+			return
+		}
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":"%s"}}]}`, name)
+	}))
+	t.Cleanup(endpoint.server.Close)
+	return endpoint
+}
+
+func (e *testEndpoint) endpoint(name string, weight int) *Endpoint {
+	return &Endpoint{Name: name, Client: NewClient(WithURL(e.server.URL), WithModel(name+"-model")), Weight: weight}
+}
+
+func TestPoolFailover(t *testing.T) {
+	down := newTestEndpoint(t, "down")
+	down.server.Close()
+	overloaded := newTestEndpoint(t, "overloaded")
+	overloaded.status.Store(http.StatusServiceUnavailable)
+	limited := newTestEndpoint(t, "limited")
+	limited.status.Store(http.StatusTooManyRequests)
+	healthy := newTestEndpoint(t, "healthy")
+
+	pool, err := NewPool("", down.endpoint("down", 0), overloaded.endpoint("overloaded", 0), limited.endpoint("limited", 0), healthy.endpoint("healthy", 0))
+	require.NoError(t, err)
+
+	completion, err := NewClient(WithPool(pool)).Complete(testMessages)
+	require.NoError(t, err)
+	assert.Equal(t, "healthy", completion.Content)
+	assert.Equal(t, "healthy", completion.Endpoint)
+	assert.Equal(t, "healthy-model", pool.Endpoint("healthy").Client.Model)
+	assert.Nil(t, pool.Endpoint("missing"))
+
+	// Client errors are not retried on other endpoints
+	overloaded.status.Store(http.StatusBadRequest)
+	_, err = pool.Complete(testMessages)
+	assert.EqualError(t, err, "overloaded: overloaded failed")
+	assert.Equal(t, int32(1), limited.hits.Load())
+
+	// Every endpoint failing reports all errors
+	overloaded.status.Store(http.StatusBadGateway)
+	healthy.status.Store(http.StatusInternalServerError)
+	_, err = pool.Complete(testMessages)
+	assert.ErrorContains(t, err, "all endpoints failed")
+	assert.ErrorContains(t, err, "limited: limited failed")
+	assert.ErrorContains(t, err, "healthy: healthy failed")
+}
+
+func TestPoolCircuitBreaker(t *testing.T) {
+	flaky := newTestEndpoint(t, "flaky")
+	flaky.status.Store(http.StatusServiceUnavailable)
+	backup := newTestEndpoint(t, "backup")
+
+	pool, err := NewPool(BalanceFailover, flaky.endpoint("flaky", 0), backup.endpoint("backup", 0))
+	require.NoError(t, err)
+
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+	pool.Failures = 2
+	pool.Cooldown = time.Minute
+
+	for i := 0; i < 4; i++ {
+		completion, err := pool.Complete(testMessages)
+		require.NoError(t, err)
+		assert.Equal(t, "backup", completion.Endpoint)
+	}
+
+	// The circuit opened after two failures
+	assert.Equal(t, int32(2), flaky.hits.Load())
+
+	// After the cooldown a single failure opens the circuit again
+	now = now.Add(2 * time.Minute)
+	_, err = pool.Complete(testMessages)
+	require.NoError(t, err)
+	_, err = pool.Complete(testMessages)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), flaky.hits.Load())
+
+	// A success closes the circuit
+	now = now.Add(2 * time.Minute)
+	flaky.status.Store(0)
+	for i := 0; i < 2; i++ {
+		completion, err := pool.Complete(testMessages)
+		require.NoError(t, err)
+		assert.Equal(t, "flaky", completion.Endpoint)
+	}
+
+	// All circuits open
+	backup.server.Close()
+	flaky.status.Store(http.StatusServiceUnavailable)
+	for i := 0; i < 2; i++ {
+		_, err = pool.Complete(testMessages)
+		assert.ErrorContains(t, err, "all endpoints failed")
+	}
+	_, err = pool.Complete(testMessages)
+	assert.EqualError(t, err, "all endpoints are unavailable (circuit open)")
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	heavy := newTestEndpoint(t, "heavy")
+	light := newTestEndpoint(t, "light")
+
+	pool, err := NewPool(BalanceRoundRobin, heavy.endpoint("heavy", 2), light.endpoint("light", 1))
+	require.NoError(t, err)
+
+	var order []string
+	for i := 0; i < 6; i++ {
+		completion, err := pool.Complete(testMessages)
+		require.NoError(t, err)
+		order = append(order, completion.Endpoint)
+	}
+	assert.Equal(t, []string{"heavy", "light", "heavy", "heavy", "light", "heavy"}, order)
+
+	// Failing endpoints fail over to the others
+	light.status.Store(http.StatusTooManyRequests)
+	for i := 0; i < 3; i++ {
+		completion, err := pool.Complete(testMessages)
+		require.NoError(t, err)
+		assert.Equal(t, "heavy", completion.Endpoint)
+	}
+	assert.Equal(t, int32(3), light.hits.Load())
+
+	_, err = NewPool("random", heavy.endpoint("heavy", 1))
+	assert.Error(t, err)
+	_, err = NewPool(BalanceRoundRobin)
+	assert.Error(t, err)
+}
+
+func TestPoolSetModel(t *testing.T) {
+	healthy := newTestEndpoint(t, "healthy")
+	pool, err := NewPool("", healthy.endpoint("healthy", 0))
+	require.NoError(t, err)
+
+	// The endpoints of a pool use their own models
+	client := NewClient(WithPool(pool), WithModel("healthy-model"))
+	assert.NoError(t, client.SetModel("healthy-model"))
+	assert.Error(t, client.SetModel("other"))
+	assert.Equal(t, "healthy-model", client.Model)
+
+	client = NewClient(WithModel("a"))
+	assert.NoError(t, client.SetModel("b"))
+	assert.Equal(t, "b", client.Model)
+}
+
+func TestRetryable(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	dropped := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		conn.Close()
+	}))
+	defer dropped.Close()
+
+	untrusted := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	untrusted.Config.ErrorLog = log.New(io.Discard, "", 0)
+	untrusted.StartTLS()
+	defer untrusted.Close()
+
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	request := func(url string) error {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{name: "rate limit", err: &StatusError{StatusCode: 429}, retryable: true},
+		{name: "server error", err: &StatusError{StatusCode: 503}, retryable: true},
+		{name: "bad request", err: &StatusError{StatusCode: 400}, retryable: false},
+		{name: "timeout", err: request(slow.URL), retryable: true},
+		{name: "connection refused", err: request(closed.URL), retryable: true},
+		{name: "dropped connection", err: request(dropped.URL), retryable: true},
+		{name: "untrusted certificate", err: request(untrusted.URL), retryable: false},
+		{name: "invalid url", err: request("htp://localhost"), retryable: false},
+	}
+
+	for _, tt := range tests {
+		require.Error(t, tt.err, tt.name)
+		assert.Equal(t, tt.retryable, Retryable(fmt.Errorf("wrapped: %w", tt.err)), "%s: %v", tt.name, tt.err)
+	}
+}
@@ -1,16 +1,112 @@
 package ai
 
-// OpenAI API conform message
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Message is a single message of a conversation. Images are sent as additional
+// content parts.
 type Message struct {
+	Role    string  `json:"role"`
+	Content string  `json:"content"`
+	Images  []Image `json:"images,omitempty"`
+	// Name is the name of the participant, sent to OpenAI compatible apis
+	Name string `json:"name,omitempty"`
+	// Cache marks the message as cache breakpoint for the anthropic prompt caching
+	Cache bool `json:"cache,omitempty"`
+	// Attachments are image files of a workflow message that are attached to the
+	// rendered message
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+// Image is an image attached to a message
+type Image struct {
+	Name      string `json:"name"`
+	MediaType string `json:"media_type"`
+	Data      []byte `json:"data"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+}
+
+// DataURL returns the image as base64 encoded data url
+func (i Image) DataURL() string {
+	return fmt.Sprintf("data:%s;base64,%s", i.MediaType, base64.StdEncoding.EncodeToString(i.Data))
+}
+
+// OpenAI API conform request message. Content is either a string or a list of content parts.
+type RequestMessage struct {
 	Role    string `json:"role"`
-	Content string `json:"content"`
+	Content any    `json:"content"`
+	Name    string `json:"name,omitempty"`
+}
+
+// OpenAI API conform content part
+type ContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
 }
 
 // OpenAI API conform request
 type Request struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Temperature float64   `json:"temperature"`
+	Model       string           `json:"model"`
+	Messages    []RequestMessage `json:"messages"`
+	Temperature float64          `json:"temperature"`
+	Stream      bool             `json:"stream,omitempty"`
+
+	// Params are additional fields of the request, see Client.Params
+	Params map[string]json.RawMessage `json:"-"`
+}
+
+// MarshalJSON adds the params to the fields of the request. They can't change the
+// model, the messages and stream.
+func (r Request) MarshalJSON() ([]byte, error) {
+	type request Request
+	data, err := json.Marshal(request(r))
+	if err != nil || len(r.Params) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range r.Params {
+		if key != "model" && key != "messages" && key != "stream" {
+			fields[key] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// NewRequestMessages converts messages to the OpenAI API format. Messages with images
+// are sent as list of content parts.
+func NewRequestMessages(messages []Message) []RequestMessage {
+	converted := make([]RequestMessage, len(messages))
+	for i, msg := range messages {
+		if len(msg.Images) == 0 {
+			converted[i] = RequestMessage{Role: msg.Role, Content: msg.Content, Name: msg.Name}
+			continue
+		}
+
+		var parts []ContentPart
+		if msg.Content != "" {
+			parts = append(parts, ContentPart{Type: "text", Text: msg.Content})
+		}
+		for _, img := range msg.Images {
+			part := ContentPart{Type: "image_url"}
+			part.ImageURL = &struct {
+				URL string `json:"url"`
+			}{URL: img.DataURL()}
+			parts = append(parts, part)
+		}
+		converted[i] = RequestMessage{Role: msg.Role, Content: parts, Name: msg.Name}
+	}
+	return converted
 }
 
 // OpenAI API conform response
@@ -20,9 +116,7 @@ type Response struct {
 	Created int    `json:"created"`
 	Model   string `json:"model"`
 	Usage   struct {
-		PromptTokens            int `json:"prompt_tokens"`
-		CompletionTokens        int `json:"completion_tokens"`
-		TotalTokens             int `json:"total_tokens"`
+		Usage
 		CompletionTokensDetails struct {
 			ReasoningTokens          int `json:"reasoning_tokens"`
 			AcceptedPredictionTokens int `json:"accepted_prediction_tokens"`
@@ -36,3 +130,42 @@ type Response struct {
 		Index        int         `json:"index"`
 	} `json:"choices"`
 }
+
+// OpenAI API conform chunk of a streamed response
+type StreamChunk struct {
+	Model   string `json:"model"`
+	Usage   *Usage `json:"usage"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// Usage is the token usage of a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Completion is the result of a chat completion
+type Completion struct {
+	Content string `json:"content"`
+	Model   string `json:"model"`
+	Usage   Usage  `json:"usage"`
+
+	// Endpoint is the name of the endpoint of a pool that answered
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// StatusError is returned if the api answers with an unexpected status code. The
+// message is the body of the response.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return e.Body
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Stream sends the messages and calls onDelta with every part of the response as it
+// arrives. Providers that don't support streaming through clai (anthropic, mock,
+// pools and cassettes) call onDelta once with the whole response.
+func (c *Client) Stream(messages []Message, onDelta func(delta string)) (*Completion, error) {
+	if c.Cassette != nil || c.Pool != nil || c.isMock() || c.isAnthropic() {
+		completion, err := c.Complete(messages)
+		if err != nil {
+			return nil, err
+		}
+		onDelta(completion.Content)
+		return completion, nil
+	}
+
+	data, err := json.Marshal(Request{
+		Model:    c.Model,
+		Messages: NewRequestMessages(messages),
+		Stream:   true,
+		Params:   c.Params,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest("POST", c.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	// Some compatible apis ignore the stream parameter and answer at once
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		completion, err := decodeResponse(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		onDelta(completion.Content)
+		return completion, nil
+	}
+
+	completion := &Completion{}
+	var content strings.Builder
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk StreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("error decoding stream: %w", err)
+		}
+
+		if chunk.Model != "" {
+			completion.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			completion.Usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				onDelta(choice.Delta.Content)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading stream: %w", err)
+	}
+
+	completion.Content = content.String()
+	return completion, nil
+}
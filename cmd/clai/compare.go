@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/library"
+	"github.com/bigjk/clai/runner"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// compareClient creates the client for an entry of --models. An entry can be the name
+// of a profile, a model of a profile like "gpt-4o@openai" or a model of the active profile.
+func compareClient(entry string, requested string) (*ai.Client, error) {
+	if viper.IsSet("profiles." + entry) {
+		return newProfileClient(entry, false)
+	}
+
+	if model, profile, ok := strings.Cut(entry, "@"); ok {
+		client, err := newProfileClient(profile, false)
+		if err != nil {
+			return nil, err
+		}
+		return client, client.SetModel(model)
+	}
+
+	client, err := newClient(requested)
+	if err != nil {
+		return nil, err
+	}
+	return client, client.SetModel(entry)
+}
+
+func compareCmd() *cobra.Command {
+	var (
+		workingDir  string
+		outFile     string
+		format      string
+		models      []string
+		seed        int64
+		unsafePaths bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "compare [file] [input...]",
+		Short: "Send the same rendered messages to several models and compare their responses",
+		Args:  cobra.MinimumNArgs(2),
+		// The file can also be the name of a workflow in the workflow_dirs
+		ValidArgsFunction: completeWorkflowArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(models) == 0 {
+				return fmt.Errorf("no models to compare, use --models a,b")
+			}
+
+			if format == "" {
+				format = "md"
+				if ext := strings.ToLower(filepath.Ext(outFile)); ext == ".html" || ext == ".htm" {
+					format = "html"
+				}
+			}
+			if format != "md" && format != "html" {
+				return fmt.Errorf("unknown format %q, expected md or html", format)
+			}
+
+			file, content, workflow, err := readWorkflow(args[0])
+			if err != nil {
+				return err
+			}
+			input := library.ArgsInput(args[1:], workflow.Meta.Inputs)
+
+			client, err := newClient(workflow.Meta.Profile)
+			if err != nil {
+				return err
+			}
+
+			r := &runner.Runner{
+				Workflow:   workflow,
+				Client:     client,
+				WorkingDir: workingDir,
+				Options:    executorOptions(client, unsafePaths),
+			}
+			if seed == 0 {
+				seed = runner.NewSeed()
+			}
+
+			// Render once, so every model gets exactly the same messages
+			messages, err := r.Render(input, seed)
+			if err != nil {
+				return err
+			}
+
+			clients := make([]*ai.Client, len(models))
+			for i, model := range models {
+				if clients[i], err = compareClient(strings.TrimSpace(model), workflow.Meta.Profile); err != nil {
+					return err
+				}
+			}
+
+			results := r.Compare(input, seed, messages, clients)
+			recordHistory("compare", file, content, workingDir, results)
+
+			var report bytes.Buffer
+			if format == "html" {
+				err = runner.WriteCompareHTML(&report, results)
+			} else {
+				err = runner.WriteCompareMarkdown(&report, results)
+			}
+			if err != nil {
+				return fmt.Errorf("error writing report: %w", err)
+			}
+
+			if outFile != "" {
+				if err := os.WriteFile(outFile, report.Bytes(), 0644); err != nil {
+					return fmt.Errorf("error writing report file: %w", err)
+				}
+			} else {
+				fmt.Print(report.String())
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&workingDir, "working_dir", "./", "Working directory for the command")
+	cmd.Flags().StringVar(&outFile, "out", "", "Report file path (if not specified, prints to stdout)")
+	cmd.Flags().StringVar(&format, "format", "", "Format of the report: md or html (default from the --out extension, else md)")
+	cmd.Flags().StringSliceVar(&models, "models", nil, "Comma separated list of models to compare, entries can be profiles or model@profile")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "Seed for the random helpers (0 picks a random seed)")
+	cmd.Flags().BoolVar(&unsafePaths, "unsafe-paths", false, "Allow helpers to read files outside of the working directory and allowed paths")
+	return cmd
+}
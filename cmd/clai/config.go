@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// keyName returns the name to store a key under, the argument or the active profile
+func keyName(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	profile, _ := activeProfile("")
+	return storeName(profile)
+}
+
+func configCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the api keys in the encrypted key store",
+	}
+
+	setKey := &cobra.Command{
+		Use:   "set-key [name]",
+		Short: "Store an api key read from stdin (name defaults to the active profile or \"default\")",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := keyName(args)
+
+			// The key is read from stdin, so it doesn't end up in the shell history.
+			// On a terminal it isn't echoed.
+			fmt.Fprintf(os.Stderr, "API key for %q: ", name)
+			line, err := readKey(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("error reading api key: %w", err)
+			}
+
+			key := strings.TrimSpace(line)
+			if key == "" {
+				return fmt.Errorf("api key is empty")
+			}
+
+			store := keyStore()
+			if err := store.Set(name, key); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "\nStored api key %q in %s\n", name, store.Dir)
+			return nil
+		},
+	}
+
+	deleteKey := &cobra.Command{
+		Use:   "delete-key [name]",
+		Short: "Remove an api key from the key store",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return keyStore().Delete(keyName(args))
+		},
+	}
+
+	keys := &cobra.Command{
+		Use:   "keys",
+		Short: "List the names of the stored api keys",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := keyStore().Names()
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				fmt.Println(name)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(setKey, deleteKey, keys)
+	return cmd
+}
+
+// readKey reads a line from the file without echoing it if it is a terminal
+func readKey(file *os.File) (string, error) {
+	if fd := int(file.Fd()); term.IsTerminal(fd) {
+		key, err := term.ReadPassword(fd)
+		return string(key), err
+	}
+
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return line, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bigjk/clai/templating"
+	"github.com/spf13/cobra"
+)
+
+func convertCmd() *cobra.Command {
+	var (
+		out string
+		to  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "convert [workflow]",
+		Short: "Convert a workflow between the markdown and the YAML or JSON format",
+		Long: `Convert a workflow between the markdown and the YAML or JSON format. Markdown workflows
+are converted to YAML, YAML and JSON workflows to markdown, unless the format is given
+with --to or by the extension of --out. Converting back gives the same workflow.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeWorkflowArgs(cmd, nil, toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := resolveWorkflow(args[0])
+			if err != nil {
+				return err
+			}
+
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("error reading file: %w", err)
+			}
+
+			doc, err := templating.ParseDocument(file, string(content))
+			if err != nil {
+				return err
+			}
+
+			if to == "" {
+				switch ext := strings.ToLower(filepath.Ext(out)); {
+				case ext == ".json":
+					to = "json"
+				case ext == ".yaml" || ext == ".yml":
+					to = "yaml"
+				case ext == ".md":
+					to = "md"
+				case templating.IsStructured(file):
+					to = "md"
+				default:
+					to = "yaml"
+				}
+			}
+
+			var converted string
+			switch to {
+			case "md", "markdown":
+				converted, err = doc.Markdown()
+			case "yaml", "yml":
+				converted, err = doc.YAML()
+			case "json":
+				converted, err = doc.JSON()
+			default:
+				return fmt.Errorf("unknown format %q, expected md, yaml or json", to)
+			}
+			if err != nil {
+				return fmt.Errorf("error converting %s: %w", file, err)
+			}
+
+			if out == "" {
+				fmt.Print(converted)
+				return nil
+			}
+			if err := os.WriteFile(out, []byte(converted), 0644); err != nil {
+				return fmt.Errorf("error writing file: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&out, "out", "", "Output file path (if not specified, prints to stdout)")
+	cmd.Flags().StringVar(&to, "to", "", "Target format, md, yaml or json")
+
+	return cmd
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/eval"
+	"github.com/spf13/cobra"
+)
+
+func evalCmd() *cobra.Command {
+	var (
+		junitFile    string
+		cassetteFile string
+		cassetteMode string
+		concurrency  int
+		unsafePaths  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "eval [suite]",
+		Short: "Run the test cases of a suite and check the responses with assertions",
+		Args:  cobra.ExactArgs(1),
+		// Failing cases are reported, the usage would only hide them
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			suite, err := eval.LoadSuite(args[0])
+			if err != nil {
+				return err
+			}
+			if concurrency > 0 {
+				suite.Concurrency = concurrency
+			}
+
+			var cassette *ai.Cassette
+			if cassetteFile != "" {
+				if cassette, err = ai.LoadCassette(cassetteFile, cassetteMode); err != nil {
+					return err
+				}
+			}
+
+			clients := func(profile string) (*ai.Client, error) {
+				client, err := newClient(profile)
+				if err != nil {
+					return nil, err
+				}
+				client.Cassette = cassette
+				return client, nil
+			}
+
+			results := suite.Run(clients, executorOptions(nil, unsafePaths)...)
+			if err := eval.WriteText(os.Stdout, suite, results); err != nil {
+				return err
+			}
+
+			if junitFile != "" {
+				f, err := os.Create(junitFile)
+				if err != nil {
+					return fmt.Errorf("error creating junit report: %w", err)
+				}
+				defer f.Close()
+
+				if err := eval.WriteJUnit(f, suite, results); err != nil {
+					return fmt.Errorf("error writing junit report: %w", err)
+				}
+			}
+
+			summary := eval.Summarize(results)
+			if summary.Failed+summary.Errors > 0 {
+				return fmt.Errorf("%d of %d cases failed", summary.Failed+summary.Errors, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&junitFile, "junit", "", "Write a JUnit XML report to the file")
+	cmd.Flags().StringVar(&cassetteFile, "cassette", "", "Record the responses to the file and replay them in later runs")
+	cmd.Flags().StringVar(&cassetteMode, "cassette_mode", ai.CassetteAuto, "auto replays recorded and records new responses, record always sends requests, replay never does")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 0, "Number of cases run in parallel (default from the suite or 4)")
+	cmd.Flags().BoolVar(&unsafePaths, "unsafe-paths", false, "Allow helpers to read files outside of the working directory and allowed paths")
+	return cmd
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bigjk/clai/history"
+	"github.com/bigjk/clai/runner"
+	"github.com/bigjk/clai/templating"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// workflowState describes if the workflow of an entry still exists unchanged
+func workflowState(entry *history.Entry) string {
+	content, err := os.ReadFile(entry.Workflow)
+	if err != nil {
+		return "missing"
+	}
+	if history.HashWorkflow(content) != entry.WorkflowHash {
+		return "changed"
+	}
+	return "unchanged"
+}
+
+// truncate shortens a single line preview of the text to n characters
+func truncate(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len([]rune(text)) > n {
+		return string([]rune(text)[:n-3]) + "..."
+	}
+	return text
+}
+
+// formatRun formats the parameters and the usage of a run
+func formatRun(result *runner.Result) string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("Input: %s\n", result.Input))
+	out.WriteString(fmt.Sprintf("Seed: %d\n", result.Seed))
+	if result.Profile != "" {
+		out.WriteString(fmt.Sprintf("Profile: %s\n", result.Profile))
+	}
+	out.WriteString(fmt.Sprintf("Model: %s\n", result.Model))
+	out.WriteString(fmt.Sprintf("URL: %s\n", result.URL))
+	if result.Endpoint != "" {
+		out.WriteString(fmt.Sprintf("Endpoint: %s\n", result.Endpoint))
+	}
+	out.WriteString(fmt.Sprintf("Started: %s (%d ms)\n", result.StartedAt.Format("2006-01-02 15:04:05"), result.DurationMS))
+	out.WriteString(fmt.Sprintf("Usage: %d prompt + %d completion = %d tokens\n", result.Usage.PromptTokens, result.Usage.CompletionTokens, result.Usage.TotalTokens))
+	if result.Error != "" {
+		out.WriteString(fmt.Sprintf("Error: %s\n", result.Error))
+	}
+	return out.String()
+}
+
+func historyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Inspect and replay previous runs",
+	}
+
+	store := func() *history.Store {
+		return history.NewStore(viper.GetString("history_dir"))
+	}
+
+	var limit int
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the recorded runs, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := store().List(limit)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tCOMMAND\tRUNS\tWORKFLOW\tINPUT")
+			for _, entry := range entries {
+				input := ""
+				if len(entry.Runs) > 0 {
+					input = truncate(entry.Runs[0].Input, 40)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", entry.ID, entry.Command, len(entry.Runs), filepath.Base(entry.Workflow), input)
+			}
+			return w.Flush()
+		},
+	}
+	list.Flags().IntVar(&limit, "limit", 20, "Maximum number of entries to show (0 shows all)")
+
+	var (
+		showRun      int
+		showMessages bool
+	)
+	show := &cobra.Command{
+		Use:   "show [id]",
+		Short: "Show a recorded invocation with its runs (id can be a prefix or \"last\")",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entry, err := store().Get(args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("ID: %s\n", entry.ID)
+			fmt.Printf("Command: %s\n", entry.Command)
+			fmt.Printf("Created: %s\n", entry.CreatedAt.Format("2006-01-02 15:04:05"))
+			fmt.Printf("Workflow: %s (%s)\n", entry.Workflow, workflowState(entry))
+			fmt.Printf("Workflow hash: %s\n", entry.WorkflowHash)
+			fmt.Printf("Working dir: %s\n", entry.WorkingDir)
+
+			for i := range entry.Runs {
+				if showRun > 0 && showRun != i+1 {
+					continue
+				}
+
+				result := &entry.Runs[i]
+				fmt.Printf("\n====== Run %d\n", i+1)
+				fmt.Print(formatRun(result))
+				if showMessages {
+					fmt.Printf("\n%s", formatMessages(result.Messages))
+				}
+				fmt.Printf("\nResponse:\n%s\n", result.Response)
+			}
+			return nil
+		},
+	}
+	show.Flags().IntVar(&showRun, "run", 0, "Only show the run with this number")
+	show.Flags().BoolVar(&showMessages, "messages", false, "Show the rendered messages")
+
+	var (
+		rerunOut   string
+		rerunModel string
+	)
+	rerun := &cobra.Command{
+		Use:   "rerun [id[:run]]",
+		Short: "Send the stored messages of a run again",
+		Long: `Send the stored messages of a run again. The messages are not rendered again, so the
+result is independent of the current state of the working directory. The profile, url and
+model of the run are used unless --profile or --model is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entry, stored, err := store().Resolve(args[0])
+			if err != nil {
+				return err
+			}
+
+			client, err := newClient(stored.Profile)
+			if err != nil {
+				return err
+			}
+			switch endpoint := client.Pool.Endpoint(stored.Endpoint); {
+			case endpoint != nil:
+				// Runs of a pool are sent to the endpoint that answered them
+				forward := *endpoint.Client
+				forward.Profile = client.Profile
+				client = &forward
+			case client.Pool != nil:
+				if stored.Model != client.Model {
+					fmt.Fprintf(os.Stderr, "warning: the run used model %s, the endpoints of the pool use their own models\n", stored.Model)
+				}
+			case profileFlag == "" && stored.URL != "":
+				// Runs recorded without the profile may have used another one, the
+				// url they were sent to is known though
+				client.URL = stored.URL
+			}
+
+			if client.Pool == nil {
+				client.Model = stored.Model
+			}
+			if rerunModel != "" {
+				if err := client.SetModel(rerunModel); err != nil {
+					return err
+				}
+			}
+
+			// The post-processing is only applied if the workflow is still the same
+			r := &runner.Runner{Client: client}
+			if content, err := os.ReadFile(entry.Workflow); err == nil && history.HashWorkflow(content) == entry.WorkflowHash {
+				if r.Workflow, err = templating.ParseWorkflowFile(entry.Workflow, string(content)); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintf(os.Stderr, "warning: workflow %s is %s since the run, returning the unprocessed response\n", entry.Workflow, workflowState(entry))
+			}
+
+			res := &runner.Result{
+				Index:    1,
+				Seed:     stored.Seed,
+				Input:    stored.Input,
+				Messages: stored.Messages,
+			}
+			err = r.Send(res)
+			if err != nil {
+				res.Error = err.Error()
+			}
+
+			rerunEntry := history.NewEntry("rerun", entry.Workflow, nil, entry.WorkingDir)
+			rerunEntry.WorkflowHash = entry.WorkflowHash
+			rerunEntry.Runs = []runner.Result{*res}
+			if viper.GetBool("history") {
+				if err := store().Save(rerunEntry); err != nil {
+					fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+				}
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if rerunOut != "" {
+				if err := os.WriteFile(rerunOut, []byte(res.Response), 0644); err != nil {
+					return fmt.Errorf("error writing result file: %w", err)
+				}
+				return nil
+			}
+
+			fmt.Println(res.Response)
+			return nil
+		},
+	}
+	rerun.Flags().StringVar(&rerunOut, "out", "", "Output file path (if not specified, prints to stdout)")
+	rerun.Flags().StringVar(&rerunModel, "model", "", "Send the messages to another model")
+
+	var diffMessages bool
+	diff := &cobra.Command{
+		Use:   "diff [id[:run]] [id[:run]]",
+		Short: "Compare the parameters and responses of two runs",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entryA, a, err := store().Resolve(args[0])
+			if err != nil {
+				return err
+			}
+			entryB, b, err := store().Resolve(args[1])
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Parameters:")
+			params := [][3]string{
+				{"workflow", entryA.Workflow, entryB.Workflow},
+				{"workflow_hash", entryA.WorkflowHash, entryB.WorkflowHash},
+				{"input", a.Input, b.Input},
+				{"seed", fmt.Sprint(a.Seed), fmt.Sprint(b.Seed)},
+				{"profile", a.Profile, b.Profile},
+				{"model", a.Model, b.Model},
+				{"url", a.URL, b.URL},
+				{"total_tokens", fmt.Sprint(a.Usage.TotalTokens), fmt.Sprint(b.Usage.TotalTokens)},
+			}
+			for _, param := range params {
+				if param[1] == param[2] {
+					fmt.Printf("  %s: %s\n", param[0], param[1])
+				} else {
+					fmt.Printf("  %s: %s -> %s\n", param[0], param[1], param[2])
+				}
+			}
+
+			if diffMessages {
+				fmt.Printf("\nMessages:\n%s", history.Diff(formatMessages(a.Messages), formatMessages(b.Messages)))
+			} else if formatMessages(a.Messages) != formatMessages(b.Messages) {
+				fmt.Println("\nMessages differ (use --messages to show the diff)")
+			} else {
+				fmt.Println("\nMessages are identical")
+			}
+
+			fmt.Printf("\nResponse:\n%s", history.Diff(a.Response, b.Response))
+			return nil
+		},
+	}
+	diff.Flags().BoolVar(&diffMessages, "messages", false, "Show the diff of the rendered messages")
+
+	cmd.AddCommand(list, show, rerun, diff)
+	return cmd
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bigjk/clai/examples"
+	"github.com/bigjk/clai/library"
+	"github.com/bigjk/clai/templating"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// workflowLibrary returns the library of the workflow_dirs. Relative directories are
+// resolved against the directory of the config file.
+func workflowLibrary() *library.Library {
+	base := "."
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		base = filepath.Dir(configFile)
+	}
+	return library.New(base, viper.GetStringSlice("workflow_dirs"))
+}
+
+// resolveWorkflow returns the file of a workflow given as path or as name in the
+// workflow_dirs. Existing files take precedence.
+func resolveWorkflow(arg string) (string, error) {
+	if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+		return arg, nil
+	}
+
+	file, err := workflowLibrary().Find(arg)
+	if err != nil {
+		return "", fmt.Errorf("no workflow file %s: %w", arg, err)
+	}
+	return file, nil
+}
+
+// readWorkflow resolves and parses the workflow given as path or name
+func readWorkflow(arg string) (string, []byte, *templating.Workflow, error) {
+	file, err := resolveWorkflow(arg)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	workflow, err := templating.ParseWorkflowFile(file, string(content))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return file, content, workflow, nil
+}
+
+// completeWorkflowArgs completes the names of the workflows in the workflow_dirs for
+// the first argument and the declared inputs as "key=" for the following ones
+func completeWorkflowArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		entries, err := workflowLibrary().List()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+
+		var names []string
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name, toComplete) {
+				names = append(names, entry.Name+"\t"+entry.Meta.Description)
+			}
+		}
+		// Fall back to file completion for paths
+		return names, cobra.ShellCompDirectiveDefault
+	}
+
+	_, _, workflow, err := readWorkflow(args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	used := map[string]bool{}
+	for _, arg := range args[1:] {
+		key, _, _ := strings.Cut(arg, "=")
+		used[key] = true
+	}
+
+	var keys []string
+	for _, input := range workflow.Meta.Inputs {
+		if !used[input.Name] && strings.HasPrefix(input.Name+"=", toComplete) {
+			keys = append(keys, input.Name+"=\t"+input.Description)
+		}
+	}
+	return keys, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// formatInputs formats the declared inputs for the listing, required inputs are
+// marked with a *
+func formatInputs(inputs []templating.Input) string {
+	var names []string
+	for _, input := range inputs {
+		if input.Required {
+			names = append(names, input.Name+"*")
+		} else {
+			names = append(names, input.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func listCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the workflows of the workflow_dirs with description and inputs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lib := workflowLibrary()
+			if len(lib.Dirs) == 0 {
+				return fmt.Errorf("no workflow_dirs configured in the config file")
+			}
+
+			entries, err := lib.List()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tDESCRIPTION\tINPUTS")
+			for _, entry := range entries {
+				description := entry.Meta.Description
+				if entry.Err != nil {
+					description = "error: " + entry.Err.Error()
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Name, truncate(description, 60), formatInputs(entry.Meta.Inputs))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// templateNames returns the names of the built-in templates
+func templateNames() []string {
+	files, _ := fs.Glob(examples.Templates, "*.md")
+
+	var names []string
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(file, ".md"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newCmd() *cobra.Command {
+	var (
+		from  string
+		dir   string
+		force bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "new [name]",
+		Short: "Create a new workflow from a built-in template",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content, err := examples.Templates.ReadFile(from + ".md")
+			if err != nil {
+				return fmt.Errorf("unknown template %q, available templates: %s", from, strings.Join(templateNames(), ", "))
+			}
+
+			// New workflows go to the first workflow dir by default
+			if dir == "" {
+				dir = "."
+				if dirs := workflowLibrary().Dirs; len(dirs) > 0 {
+					dir = dirs[0]
+				}
+			}
+
+			name := strings.TrimSuffix(filepath.FromSlash(args[0]), ".md")
+			if !filepath.IsLocal(name) {
+				return fmt.Errorf("invalid workflow name %q", args[0])
+			}
+
+			file := filepath.Join(dir, name+".md")
+			if _, err := os.Stat(file); err == nil && !force {
+				return fmt.Errorf("%s already exists, use --force to overwrite it", file)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				return fmt.Errorf("error creating directory: %w", err)
+			}
+			if err := os.WriteFile(file, content, 0644); err != nil {
+				return fmt.Errorf("error writing workflow: %w", err)
+			}
+
+			fmt.Printf("Created %s\n", file)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "simple", "Built-in template to start from")
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory to create the workflow in (default the first of the workflow_dirs, else the current directory)")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing workflow")
+	cmd.RegisterFlagCompletionFunc("from", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return templateNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+	return cmd
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bigjk/clai/lint"
+	"github.com/bigjk/clai/templating"
+	"github.com/spf13/cobra"
+)
+
+func lintCmd() *cobra.Command {
+	var (
+		workingDir string
+		strict     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lint [workflows...]",
+		Short: "Check workflows for invalid roles, template errors, wrong helper calls and missing files",
+		Args:  cobra.MinimumNArgs(1),
+		// Every argument is a workflow
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeWorkflowArgs(cmd, nil, toComplete)
+		},
+		// The issues are reported, the usage would only hide them
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			errors, warnings := 0, 0
+			for _, arg := range args {
+				file, err := resolveWorkflow(arg)
+				if err != nil {
+					return err
+				}
+
+				content, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("error reading file: %w", err)
+				}
+
+				var issues []lint.Issue
+				if templating.IsStructured(file) {
+					issues = lint.LintStructured(string(content), workingDir)
+				} else {
+					issues = lint.Lint(string(content), workingDir)
+				}
+
+				for _, issue := range issues {
+					fmt.Printf("%s:%s\n", file, issue)
+					if issue.Severity == lint.Error {
+						errors++
+					} else {
+						warnings++
+					}
+				}
+			}
+
+			if errors > 0 || (strict && warnings > 0) {
+				return fmt.Errorf("found %d errors and %d warnings", errors, warnings)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&workingDir, "working_dir", "./", "Working directory the referenced files are resolved against")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail on warnings too")
+
+	return cmd
+}
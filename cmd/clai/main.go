@@ -1,23 +1,44 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bigjk/clai/ai"
 	"github.com/bigjk/clai/executor"
+	"github.com/bigjk/clai/history"
+	"github.com/bigjk/clai/library"
+	"github.com/bigjk/clai/runner"
 	"github.com/bigjk/clai/templating"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	URL    string
-	APIKey string
-	Model  string
+	URL          string
+	APIKey       string
+	APIKeyCmd    string `mapstructure:"apikey_cmd"`
+	APIKeyFile   string `mapstructure:"apikey_file"`
+	Model        string
+	AllowedPaths []string `mapstructure:"allowed_paths"`
+	CacheDir     string   `mapstructure:"cache_dir"`
+
+	EmbeddingURL   string `mapstructure:"embedding_url"`
+	EmbeddingModel string `mapstructure:"embedding_model"`
+
+	History    bool   `mapstructure:"history"`
+	HistoryDir string `mapstructure:"history_dir"`
+	SecretsDir string `mapstructure:"secrets_dir"`
+
+	WorkflowDirs []string `mapstructure:"workflow_dirs"`
+
+	Default  string             `mapstructure:"default"`
+	Profiles map[string]Profile `mapstructure:"profiles"`
 }
 
 var Version = "dev"
@@ -40,6 +61,15 @@ func loadConfig() (*Config, error) {
 	viper.SetDefault("url", "")
 	viper.SetDefault("apikey", "")
 	viper.SetDefault("model", "")
+	viper.SetDefault("allowed_paths", []string{})
+	viper.SetDefault("cache_dir", "")
+	viper.SetDefault("embedding_url", "")
+	viper.SetDefault("embedding_model", "")
+	viper.SetDefault("history", true)
+	viper.SetDefault("history_dir", "")
+	viper.SetDefault("secrets_dir", "")
+	viper.SetDefault("workflow_dirs", []string{})
+	viper.SetDefault("default", "")
 
 	// Bind environment variables
 	viper.SetEnvPrefix("CLAI")
@@ -49,6 +79,8 @@ func loadConfig() (*Config, error) {
 	viper.BindEnv("url", "CLAI_URL")
 	viper.BindEnv("apikey", "CLAI_APIKEY")
 	viper.BindEnv("model", "CLAI_MODEL")
+	viper.BindEnv("embedding_url", "CLAI_EMBEDDING_URL")
+	viper.BindEnv("embedding_model", "CLAI_EMBEDDING_MODEL")
 
 	// Read config file (ignore if not found)
 	if err := viper.ReadInConfig(); err != nil {
@@ -79,10 +111,10 @@ func createConfigCmd() *cobra.Command {
 				return fmt.Errorf("cannot use both --openai and --open_router flags")
 			}
 
+			// The api key isn't written to the config, so it doesn't end up in a repository
 			config := map[string]string{
-				"url":    "",
-				"apikey": "",
-				"model":  "",
+				"url":   "",
+				"model": "",
 			}
 
 			if useOpenAI {
@@ -97,7 +129,12 @@ func createConfigCmd() *cobra.Command {
 				viper.Set(k, v)
 			}
 
-			return viper.WriteConfig()
+			if err := viper.WriteConfig(); err != nil {
+				return err
+			}
+
+			fmt.Println("Created .clairc, store the api key with 'clai config set-key' or set apikey_cmd or apikey_file")
+			return nil
 		},
 	}
 
@@ -107,52 +144,170 @@ func createConfigCmd() *cobra.Command {
 	return cmd
 }
 
+// formatMessages formats messages for the dry run preview. Attached images are shown
+// as placeholders.
+func formatMessages(messages []ai.Message) string {
+	var result strings.Builder
+	for i, msg := range messages {
+		result.WriteString(fmt.Sprintf("Message %d:\n", i+1))
+		result.WriteString(fmt.Sprintf("Role: %s\n", msg.Role))
+		result.WriteString(fmt.Sprintf("Content:\n%s\n", msg.Content))
+		for _, img := range msg.Images {
+			result.WriteString(fmt.Sprintf("[Image: %s, %s, %dx%d, %.1f KB]\n", img.Name, img.MediaType, img.Width, img.Height, float64(len(img.Data))/1024))
+		}
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+// saveRawResponse saves the unprocessed response next to the output file if enabled by
+// the flag or the workflow.
+func saveRawResponse(meta templating.Meta, raw string, outFile string, saveRaw bool) error {
+	if !(saveRaw || meta.SaveRaw) || outFile == "" || raw == "" {
+		return nil
+	}
+
+	ext := filepath.Ext(outFile)
+	rawFile := strings.TrimSuffix(outFile, ext) + ".raw" + ext
+	if err := os.WriteFile(rawFile, []byte(raw), 0644); err != nil {
+		return fmt.Errorf("error writing raw response file: %w", err)
+	}
+	return nil
+}
+
+// recordHistory saves the runs of an invocation to the history. Failing to record
+// the history is reported but doesn't fail the command.
+func recordHistory(command string, file string, content []byte, workingDir string, results []*runner.Result) {
+	if !viper.GetBool("history") {
+		return
+	}
+
+	entry := history.NewEntry(command, file, content, workingDir)
+	for _, result := range results {
+		if result != nil {
+			entry.Runs = append(entry.Runs, *result)
+		}
+	}
+	if len(entry.Runs) == 0 {
+		return
+	}
+
+	if err := history.NewStore(viper.GetString("history_dir")).Save(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
+
+// judgeCandidates runs the judge workflow with the profile it requests
+func judgeCandidates(judge *templating.Workflow, input string, seed int64, candidates []runner.Candidate, workingDir string, unsafePaths bool) (*runner.Verdict, error) {
+	client, err := newClient(judge.Meta.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("judge: %w", err)
+	}
+
+	r := &runner.Runner{Workflow: judge, Client: client, WorkingDir: workingDir, Options: executorOptions(client, unsafePaths)}
+	return r.Judge(input, seed, candidates)
+}
+
+// writeVerdict writes the winning result of the judge in the format of the output and
+// the scores of all candidates next to it
+func writeVerdict(verdict *runner.Verdict, results []*runner.Result, files []string, output *runner.Output, bestFile string) error {
+	for i := range verdict.Scores {
+		verdict.Scores[i].File = files[verdict.Scores[i].Index-1]
+	}
+
+	winner := results[verdict.Winner-1]
+	data, err := output.Encode(winner, winner.Response)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(bestFile, data, 0644); err != nil {
+		return fmt.Errorf("error writing best result file: %w", err)
+	}
+
+	data, err = json.MarshalIndent(verdict, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding scores: %w", err)
+	}
+
+	scoresFile := strings.TrimSuffix(bestFile, filepath.Ext(bestFile)) + ".scores.json"
+	if err := os.WriteFile(scoresFile, data, 0644); err != nil {
+		return fmt.Errorf("error writing scores file: %w", err)
+	}
+
+	for _, score := range verdict.Scores {
+		marker := " "
+		if score.Index == verdict.Winner {
+			marker = "*"
+		}
+		fmt.Printf("%s run %d: %g\n", marker, score.Index, score.Score)
+	}
+	fmt.Printf("Best response (run %d) written to %s\n", verdict.Winner, bestFile)
+	return nil
+}
+
 func runCmd() *cobra.Command {
 	var (
-		workingDir string
-		outFile    string
-		dryRun     bool
+		workingDir  string
+		outFile     string
+		dryRun      bool
+		unsafePaths bool
+		saveRaw     bool
+		seed        int64
 	)
 
 	cmd := &cobra.Command{
 		Use:   "run [file] [input...]",
 		Short: "Run a file with the given input",
 		Args:  cobra.MinimumNArgs(2),
+		// The file can also be the name of a workflow in the workflow_dirs
+		ValidArgsFunction: completeWorkflowArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			file := args[0]
-			input := strings.Join(args[1:], " ")
-
-			content, err := os.ReadFile(file)
+			file, content, workflow, err := readWorkflow(args[0])
 			if err != nil {
-				return fmt.Errorf("error reading file: %w", err)
+				return err
 			}
+			input := library.ArgsInput(args[1:], workflow.Meta.Inputs)
 
-			messages := templating.ParseTemplate(string(content))
-
-			finalMessages, err := executor.Execute(messages, input, workingDir)
+			client, err := newClient(workflow.Meta.Profile)
 			if err != nil {
-				return fmt.Errorf("error executing command: %w", err)
+				return err
+			}
+
+			r := &runner.Runner{
+				Workflow:   workflow,
+				Client:     client,
+				WorkingDir: workingDir,
+				Options:    executorOptions(client, unsafePaths),
+			}
+			if seed == 0 {
+				seed = runner.NewSeed()
 			}
 
 			var result string
 			if dryRun {
-				// Format messages for preview
-				result = "Messages that would be sent to API:\n\n"
-				for i, msg := range finalMessages {
-					result += fmt.Sprintf("Message %d:\n", i+1)
-					result += fmt.Sprintf("Role: %s\n", msg.Role)
-					result += fmt.Sprintf("Content:\n%s\n\n", msg.Content)
+				finalMessages, err := r.Render(input, seed)
+				if err != nil {
+					return err
 				}
+
+				// Format messages for preview
+				result = "Messages that would be sent to API:\n\n" + formatMessages(finalMessages)
 			} else {
-				res, err := ai.NewClient(
-					ai.WithAPIKey(viper.GetString("apikey")),
-					ai.WithModel(viper.GetString("model")),
-					ai.WithURL(viper.GetString("url")),
-				).Do(finalMessages)
+				res, err := r.Run(input, seed)
+				if res != nil {
+					if err != nil {
+						res.Error = err.Error()
+					}
+					recordHistory("run", file, content, workingDir, []*runner.Result{res})
+
+					if err := saveRawResponse(workflow.Meta, res.Raw, outFile, saveRaw); err != nil {
+						return err
+					}
+				}
 				if err != nil {
-					return fmt.Errorf("error getting response: %w", err)
+					return err
 				}
-				result = res
+				result = res.Response
 			}
 
 			if outFile != "" {
@@ -170,38 +325,86 @@ func runCmd() *cobra.Command {
 	cmd.Flags().StringVar(&workingDir, "working_dir", "./", "Working directory for the command")
 	cmd.Flags().StringVar(&outFile, "out", "", "Output file path (if not specified, prints to stdout)")
 	cmd.Flags().BoolVar(&dryRun, "dry", false, "Preview messages without sending to API")
+	cmd.Flags().BoolVar(&saveRaw, "save_raw", false, "Save the unprocessed response next to the output file")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "Seed for the random helpers (0 picks a random seed)")
+	cmd.Flags().BoolVar(&unsafePaths, "unsafe-paths", false, "Allow helpers to read files outside of the working directory and allowed paths")
 	return cmd
 }
 
 func runMultipleCmd() *cobra.Command {
 	var (
-		workingDir string
-		outDir     string
-		numRuns    int
-		dryRun     bool
+		workingDir  string
+		outDir      string
+		numRuns     int
+		dryRun      bool
+		unsafePaths bool
+		saveRaw     bool
+		seed        int64
+		outPattern  string
+		collision   string
+		format      string
+		judgeFile   string
+		bestFile    string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "run_multiple [file] [input...]",
 		Short: "Run a file multiple times with the given input and save results",
 		Args:  cobra.MinimumNArgs(2),
+		// The file can also be the name of a workflow in the workflow_dirs
+		ValidArgsFunction: completeWorkflowArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			file := args[0]
-			input := strings.Join(args[1:], " ")
+			if numRuns < 1 {
+				return fmt.Errorf("--num must be at least 1, got %d", numRuns)
+			}
+
+			file, content, workflow, err := readWorkflow(args[0])
+			if err != nil {
+				return err
+			}
+			input := library.ArgsInput(args[1:], workflow.Meta.Inputs)
+			output, err := runner.NewOutput(outDir, outPattern, format, collision)
+			if err != nil {
+				return err
+			}
+			if bestFile == "" {
+				bestFile = "best." + output.Format
+			}
 
-			content, err := os.ReadFile(file)
+			var judge *templating.Workflow
+			if judgeFile != "" {
+				if _, _, judge, err = readWorkflow(judgeFile); err != nil {
+					return fmt.Errorf("judge: %w", err)
+				}
+			}
+
+			client, err := newClient(workflow.Meta.Profile)
 			if err != nil {
-				return fmt.Errorf("error reading file: %w", err)
+				return err
 			}
 
-			messages := templating.ParseTemplate(string(content))
-			client := ai.NewClient(
-				ai.WithAPIKey(viper.GetString("apikey")),
-				ai.WithModel(viper.GetString("model")),
-				ai.WithURL(viper.GetString("url")),
+			r := &runner.Runner{
+				Workflow:   workflow,
+				Client:     client,
+				WorkingDir: workingDir,
+				Options:    executorOptions(client, unsafePaths),
+			}
+			if seed == 0 {
+				seed = runner.NewSeed()
+			}
+
+			var (
+				errors []error
+				mu     sync.Mutex
 			)
+			addError := func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				errors = append(errors, err)
+			}
 
-			var errors []error
+			results := make([]*runner.Result, numRuns)
+			files := make([]string, numRuns)
 			wg := &sync.WaitGroup{}
 			wg.Add(numRuns)
 
@@ -209,40 +412,77 @@ func runMultipleCmd() *cobra.Command {
 				go func(i int) {
 					defer wg.Done()
 
-					finalMessages, err := executor.Execute(messages, input, workingDir)
+					// Every run gets its own seed, derived from the base seed so the
+					// whole batch can be reproduced.
+					res := &runner.Result{Index: i + 1, Seed: seed + int64(i), Input: input, StartedAt: time.Now()}
+
+					messages, err := r.Render(input, res.Seed)
 					if err != nil {
-						errors = append(errors, fmt.Errorf("error executing command: %w", err))
+						addError(fmt.Errorf("run %d: %w", i+1, err))
 						return
 					}
+					res.Messages = messages
 
-					var result string
+					var content string
 					if dryRun {
 						// Format messages for preview
-						result = fmt.Sprintf("Run %d - Messages that would be sent to API:\n\n", i+1)
-						for j, msg := range finalMessages {
-							result += fmt.Sprintf("Message %d:\n", j+1)
-							result += fmt.Sprintf("Role: %s\n", msg.Role)
-							result += fmt.Sprintf("Content:\n%s\n\n", msg.Content)
-						}
+						content = fmt.Sprintf("Run %d - Messages that would be sent to API:\n\n", i+1) + formatMessages(res.Messages)
 					} else {
-						res, err := client.Do(finalMessages)
-						if err != nil {
-							errors = append(errors, fmt.Errorf("error getting response: %w", err))
+						results[i] = res
+						if err := r.Send(res); err != nil {
+							res.Error = err.Error()
+
+							// Keep the raw response of failed post-processing for inspection
+							if outFile, fileErr := output.FileName(res); fileErr == nil && output.Format == runner.FormatMarkdown {
+								_ = saveRawResponse(workflow.Meta, res.Raw, outFile, saveRaw)
+							}
+							addError(fmt.Errorf("run %d: %w", i+1, err))
 							return
 						}
-						result = res
+						content = res.Response
 					}
 
-					outFile := filepath.Join(outDir, fmt.Sprintf("res_%d.md", i+1))
-					if err := os.WriteFile(outFile, []byte(result), 0644); err != nil {
-						errors = append(errors, fmt.Errorf("error writing result file: %w", err))
+					outFile, err := output.Write(res, content)
+					if err != nil {
+						addError(fmt.Errorf("run %d: %w", i+1, err))
 						return
 					}
+					files[i] = outFile
+
+					// The json formats contain the raw response already
+					if !dryRun && outFile != "" && output.Format == runner.FormatMarkdown {
+						if err := saveRawResponse(workflow.Meta, res.Raw, outFile, saveRaw); err != nil {
+							addError(fmt.Errorf("run %d: %w", i+1, err))
+						}
+					}
 				}(i)
 			}
 
 			wg.Wait()
 
+			if !dryRun {
+				recordHistory("run_multiple", file, content, workingDir, results)
+			}
+
+			if judge != nil && !dryRun {
+				var candidates []runner.Candidate
+				for _, res := range results {
+					if res != nil && res.Error == "" {
+						candidates = append(candidates, runner.Candidate{Index: res.Index, Seed: res.Seed, Content: res.Response})
+					}
+				}
+
+				verdict, err := judgeCandidates(judge, input, seed, candidates, workingDir, unsafePaths)
+				if err != nil {
+					if verdict != nil && verdict.Judge.Raw != "" {
+						fmt.Fprintf(os.Stderr, "Judge response:\n%s\n", verdict.Judge.Raw)
+					}
+					errors = append(errors, err)
+				} else if err := writeVerdict(verdict, results, files, output, filepath.Join(outDir, bestFile)); err != nil {
+					errors = append(errors, err)
+				}
+			}
+
 			if len(errors) > 0 {
 				return fmt.Errorf("errors occurred: %v", errors)
 			}
@@ -255,6 +495,62 @@ func runMultipleCmd() *cobra.Command {
 	cmd.Flags().StringVar(&outDir, "out", "./", "Output directory for result files")
 	cmd.Flags().IntVar(&numRuns, "num", 3, "Number of times to run the workflow")
 	cmd.Flags().BoolVar(&dryRun, "dry", false, "Preview messages without sending to API")
+	cmd.Flags().BoolVar(&saveRaw, "save_raw", false, "Save the unprocessed responses next to the result files")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "Seed for the random helpers, run n uses seed+n-1 (0 picks a random seed)")
+	cmd.Flags().StringVar(&outPattern, "out-pattern", "", "Template for the result file names, e.g. \"{{ .Slug }}_{{ .Seed }}.{{ .Ext }}\" (default res_{{ .Index }}.{{ .Ext }})")
+	cmd.Flags().StringVar(&collision, "collision", "overwrite", "What to do if a result file already exists: overwrite, skip or suffix")
+	cmd.Flags().StringVar(&format, "format", "md", "Format of the result files: md, json or jsonl")
+	cmd.Flags().StringVar(&judgeFile, "judge", "", "Workflow that scores the responses, available to it as .Candidates")
+	cmd.Flags().StringVar(&bestFile, "best", "", "File in the output directory the result with the best score is written to in the --format (default best.<format>)")
+	cmd.Flags().BoolVar(&unsafePaths, "unsafe-paths", false, "Allow helpers to read files outside of the working directory and allowed paths")
+	return cmd
+}
+
+func indexCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "index",
+		Short: "Manage semantic search indexes",
+	}
+
+	var (
+		chunkSize   int
+		unsafePaths bool
+	)
+
+	build := &cobra.Command{
+		Use:   "build [dir]",
+		Short: "Create or update the semantic search index of a directory",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := executor.NewEnv(".", viper.GetStringSlice("allowed_paths"), unsafePaths)
+			if err != nil {
+				return err
+			}
+
+			env.CacheDir = viper.GetString("cache_dir")
+			if env.CacheDir == "" {
+				env.CacheDir = executor.DefaultCacheDir()
+			}
+			client, err := newClient("")
+			if err != nil {
+				return err
+			}
+			env.Embedder = client
+
+			embedded, err := env.IndexFolder(args[0], client.EmbeddingModel, chunkSize)
+			if err != nil {
+				return fmt.Errorf("error building index: %w", err)
+			}
+
+			fmt.Printf("Indexed %s, embedded %d new or changed chunks\n", args[0], embedded)
+			return nil
+		},
+	}
+
+	build.Flags().IntVar(&chunkSize, "chunk_size", 200, "Approximate number of words per chunk")
+	build.Flags().BoolVar(&unsafePaths, "unsafe-paths", false, "Allow indexing directories outside of the current directory and allowed paths")
+
+	cmd.AddCommand(build)
 	return cmd
 }
 
@@ -302,23 +598,66 @@ func varsCmd() *cobra.Command {
 			fmt.Printf("Configuration:\n")
 			fmt.Printf("Config file: %s\n\n", configSource)
 
-			// Print each value and its source
+			profile, profileSource := activeProfile("")
+			if profile != "" {
+				fmt.Printf("Profile: %s\n  source: %s\n\n", profile, profileSource)
+			} else {
+				fmt.Printf("Profile: none\n\n")
+			}
+
+			// Print each value of the active profile and its source
 			fmt.Printf("Values:\n")
-			fmt.Printf("  url: %s\n    source: %s\n", viper.GetString("url"), getSource("url"))
-
-			// Print apikey securely
-			apiKey := viper.GetString("apikey")
-			maskedKey := "not set"
-			if apiKey != "" {
-				if len(apiKey) > 8 {
-					maskedKey = apiKey[:4] + "..." + apiKey[len(apiKey)-4:]
-				} else {
-					maskedKey = "***"
+			for _, key := range profileKeys {
+				if key == "apikey" {
+					// Print only where the apikey comes from, without running commands
+					ref, err := findAPIKey(profile, true)
+					if err != nil {
+						fmt.Printf("  apikey: error\n    source: %s\n", err)
+					} else if ref.Kind == "" {
+						fmt.Printf("  apikey: not set\n")
+					} else {
+						fmt.Printf("  apikey: hidden\n    source: %s\n", ref.Describe())
+					}
+					continue
+				}
+
+				value, source := setting(profile, key, true)
+				fmt.Printf("  %s: %s\n    source: %s\n", key, value, source)
+			}
+
+			if entries, ok := viper.Get(poolPrefix(profile) + "endpoints").([]any); ok && len(entries) > 0 {
+				balance := viper.GetString(poolPrefix(profile) + "balance")
+				if balance == "" {
+					balance = ai.BalanceFailover
 				}
+				fmt.Printf("  endpoints: %d (%s)\n", len(entries), balance)
 			}
-			fmt.Printf("  apikey: %s\n    source: %s\n", maskedKey, getSource("apikey"))
 
-			fmt.Printf("  model: %s\n    source: %s\n", viper.GetString("model"), getSource("model"))
+			fmt.Printf("  allowed_paths: %v\n    source: %s\n", viper.GetStringSlice("allowed_paths"), getSource("allowed_paths"))
+			fmt.Printf("  history: %v\n    source: %s\n", viper.GetBool("history"), getSource("history"))
+			fmt.Printf("  history_dir: %s\n    source: %s\n", viper.GetString("history_dir"), getSource("history_dir"))
+
+			// Print all profiles with the values they set
+			names := profileNames()
+			if len(names) == 0 {
+				return
+			}
+
+			fmt.Printf("\nProfiles:\n")
+			for _, name := range names {
+				fmt.Printf("  %s:\n", name)
+				for _, key := range append([]string{"apikey_cmd", "apikey_file"}, profileKeys...) {
+					if !viper.IsSet("profiles." + name + "." + key) {
+						continue
+					}
+
+					value := viper.GetString("profiles." + name + "." + key)
+					if key == "apikey" {
+						value = "hidden"
+					}
+					fmt.Printf("    %s: %s\n", key, value)
+				}
+			}
 		},
 	}
 }
@@ -331,10 +670,22 @@ func main() {
 		Short:   "CLAI - Command Line AI Workflow Runner",
 		Version: Version,
 	}
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Profile of the config to use (default from CLAI_PROFILE or the default of the config)")
 
 	rootCmd.AddCommand(createConfigCmd())
+	rootCmd.AddCommand(configCmd())
 	rootCmd.AddCommand(runCmd())
 	rootCmd.AddCommand(runMultipleCmd())
+	rootCmd.AddCommand(indexCmd())
+	rootCmd.AddCommand(historyCmd())
+	rootCmd.AddCommand(evalCmd())
+	rootCmd.AddCommand(compareCmd())
+	rootCmd.AddCommand(serveCmd())
+	rootCmd.AddCommand(proxyCmd())
+	rootCmd.AddCommand(listCmd())
+	rootCmd.AddCommand(newCmd())
+	rootCmd.AddCommand(lintCmd())
+	rootCmd.AddCommand(convertCmd())
 	rootCmd.AddCommand(versionCmd())
 	rootCmd.AddCommand(varsCmd())
 
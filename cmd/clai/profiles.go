@@ -0,0 +1,398 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/executor"
+	"github.com/bigjk/clai/secrets"
+	"github.com/spf13/viper"
+)
+
+// Profile holds the api settings of a named provider. Empty values fall back to the
+// top level values of the config.
+type Profile struct {
+	URL            string `mapstructure:"url"`
+	APIKey         string `mapstructure:"apikey"`
+	APIKeyCmd      string `mapstructure:"apikey_cmd"`
+	APIKeyFile     string `mapstructure:"apikey_file"`
+	Model          string `mapstructure:"model"`
+	EmbeddingURL   string `mapstructure:"embedding_url"`
+	EmbeddingModel string `mapstructure:"embedding_model"`
+}
+
+// profileKeys are the config keys a profile can set
+var profileKeys = []string{"url", "apikey", "model", "embedding_url", "embedding_model"}
+
+// profileFlag is the value of the global --profile flag
+var profileFlag string
+
+// activeProfile returns the name of the profile to use and where the choice came from.
+// The --profile flag takes precedence over the profile requested by a workflow, which
+// takes precedence over CLAI_PROFILE and the default of the config.
+func activeProfile(requested string) (string, string) {
+	switch {
+	case profileFlag != "":
+		return profileFlag, "--profile flag"
+	case requested != "":
+		return requested, "workflow frontmatter"
+	}
+
+	if env, ok := os.LookupEnv("CLAI_PROFILE"); ok && env != "" {
+		return env, "environment variable CLAI_PROFILE"
+	}
+	if name := viper.GetString("default"); name != "" {
+		return name, configSource()
+	}
+	return "", "no profile selected"
+}
+
+// configSource describes the config file for the sources of values
+func configSource() string {
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		return fmt.Sprintf("config file (%s)", configFile)
+	}
+	return "default value"
+}
+
+// profileNames returns the names of all profiles of the config
+func profileNames() []string {
+	var names []string
+	for name := range viper.GetStringMap("profiles") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setting returns the value of a key for the profile and where it came from. If active
+// is set, environment variables take precedence over the profile, which takes
+// precedence over the top level value of the config. Other profiles, like the ones of
+// pool endpoints, keep their own values. ${NAME} in values of the config is replaced
+// by the environment variable NAME.
+func setting(profile string, key string, active bool) (string, string) {
+	envKey := "CLAI_" + strings.ToUpper(key)
+	if value, ok := os.LookupEnv(envKey); ok && active {
+		return value, "environment variable " + envKey
+	}
+
+	if profile != "" && viper.IsSet("profiles."+profile+"."+key) {
+		return secrets.Expand(viper.GetString("profiles." + profile + "." + key)), fmt.Sprintf("profile %q in %s", profile, configSource())
+	}
+
+	if viper.InConfig(key) {
+		return secrets.Expand(viper.GetString(key)), configSource()
+	}
+	return viper.GetString(key), "default value"
+}
+
+// apiKeyRef describes where the api key of a profile comes from without revealing it
+type apiKeyRef struct {
+	// Kind is apikey, apikey_cmd, apikey_file, store or empty if no key is set
+	Kind   string
+	Value  string
+	Source string
+}
+
+// keyStore returns the encrypted store of api keys
+func keyStore() *secrets.Store {
+	return secrets.NewStore(viper.GetString("secrets_dir"))
+}
+
+// storeName returns the name the api key of the profile is stored under
+func storeName(profile string) string {
+	if profile == "" {
+		return "default"
+	}
+	return profile
+}
+
+// findAPIKey returns where the api key of the profile comes from. If active is set,
+// CLAI_APIKEY takes precedence over apikey, apikey_cmd and apikey_file of the profile
+// and the top level config, the key store is used if none of them is set. Other
+// profiles, like the ones of pool endpoints, don't get CLAI_APIKEY, so it isn't sent to
+// their providers.
+func findAPIKey(profile string, active bool) (apiKeyRef, error) {
+	if value, ok := os.LookupEnv("CLAI_APIKEY"); ok && active {
+		return apiKeyRef{Kind: "apikey", Value: value, Source: "environment variable CLAI_APIKEY"}, nil
+	}
+
+	var prefixes []string
+	if profile != "" {
+		prefixes = append(prefixes, "profiles."+profile+".")
+	}
+	prefixes = append(prefixes, "")
+
+	for _, prefix := range prefixes {
+		source := configSource()
+		if prefix != "" {
+			source = fmt.Sprintf("profile %q in %s", profile, source)
+		}
+
+		for _, kind := range []string{"apikey", "apikey_cmd", "apikey_file"} {
+			if prefix == "" && !viper.InConfig(kind) {
+				continue
+			}
+			if value := secrets.Expand(viper.GetString(prefix + kind)); value != "" {
+				return apiKeyRef{Kind: kind, Value: value, Source: kind + " in " + source}, nil
+			}
+		}
+	}
+
+	store := keyStore()
+	name := storeName(profile)
+	if _, ok, err := store.Get(name); err != nil {
+		return apiKeyRef{}, err
+	} else if ok {
+		return apiKeyRef{Kind: "store", Value: name, Source: fmt.Sprintf("key %q in key store (%s)", name, store.Dir)}, nil
+	}
+	return apiKeyRef{Source: "not set"}, nil
+}
+
+// Describe returns the source of the key for display, without the key itself
+func (ref apiKeyRef) Describe() string {
+	switch ref.Kind {
+	case "apikey_cmd":
+		return fmt.Sprintf("command %q (%s)", ref.Value, ref.Source)
+	case "apikey_file":
+		return fmt.Sprintf("file %s (%s)", ref.Value, ref.Source)
+	}
+	return ref.Source
+}
+
+// Resolve returns the api key, running the command or reading the file if necessary
+func (ref apiKeyRef) Resolve() (string, error) {
+	switch ref.Kind {
+	case "apikey_cmd":
+		return secrets.Command(ref.Value)
+	case "apikey_file":
+		return secrets.ReadFile(ref.Value)
+	case "store":
+		key, _, err := keyStore().Get(ref.Value)
+		return key, err
+	}
+	return ref.Value, nil
+}
+
+// checkProfile returns an error if the profile isn't defined in the config
+func checkProfile(profile string) error {
+	if profile == "" || viper.IsSet("profiles."+profile) {
+		return nil
+	}
+	return fmt.Errorf("unknown profile %q, available profiles: %s", profile, strings.Join(profileNames(), ", "))
+}
+
+// newClient creates an api client from the config. requested is the profile a workflow
+// asks for, if any.
+func newClient(requested string) (*ai.Client, error) {
+	profile, _ := activeProfile(requested)
+	return newProfileClient(profile, true)
+}
+
+// newProfileClient creates an api client with the settings of the profile. If the
+// profile defines endpoints, the client sends the completions to a pool of them.
+// Environment variables only apply to the active profile.
+func newProfileClient(profile string, active bool) (*ai.Client, error) {
+	client, err := profileClient(profile, active)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Profile = profile
+
+	pool, err := newPool(profile)
+	if err != nil {
+		return nil, err
+	}
+	if pool != nil {
+		client.Pool = pool
+		if client.Model == "" {
+			client.Model = pool.Endpoints[0].Client.Model
+		}
+	}
+	return client, nil
+}
+
+// profileClient creates an api client with the settings of the profile, ignoring its
+// endpoints
+func profileClient(profile string, active bool) (*ai.Client, error) {
+	if err := checkProfile(profile); err != nil {
+		return nil, err
+	}
+
+	ref, err := findAPIKey(profile, active)
+	if err != nil {
+		return nil, err
+	}
+	return keyedClient(profile, ref, active)
+}
+
+// keyedClient creates an api client with the settings of the profile and the api key
+// of ref
+func keyedClient(profile string, ref apiKeyRef, active bool) (*ai.Client, error) {
+	get := func(key string) string {
+		value, _ := setting(profile, key, active)
+		return value
+	}
+
+	apiKey, err := ref.Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("error getting api key: %w", err)
+	}
+
+	return ai.NewClient(
+		ai.WithAPIKey(apiKey),
+		ai.WithModel(get("model")),
+		ai.WithURL(get("url")),
+		ai.WithEmbeddingURL(get("embedding_url")),
+		ai.WithEmbeddingModel(get("embedding_model")),
+	), nil
+}
+
+// poolPrefix returns the config prefix of the pool settings of the profile. The top
+// level settings are only used without a profile.
+func poolPrefix(profile string) string {
+	if profile == "" {
+		return ""
+	}
+	return "profiles." + profile + "."
+}
+
+// newPool creates the pool of the endpoints of the profile, or nil if it has none.
+// An endpoint is either the name of a profile or a map with the settings of a profile
+// and an optional weight:
+//
+//	endpoints:
+//	  - openrouter
+//	  - profile: openai
+//	    weight: 2
+//	  - url: http://localhost:11434/v1/chat/completions
+//	    model: llama3:8b
+func newPool(profile string) (*ai.Pool, error) {
+	prefix := poolPrefix(profile)
+	entries, ok := viper.Get(prefix + "endpoints").([]any)
+	if !ok || len(entries) == 0 {
+		return nil, nil
+	}
+
+	var endpoints []*ai.Endpoint
+	for i, entry := range entries {
+		endpoint, err := newEndpoint(entry)
+		if err != nil {
+			return nil, fmt.Errorf("error in endpoint %d: %w", i+1, err)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	pool, err := ai.NewPool(viper.GetString(prefix+"balance"), endpoints...)
+	if err != nil {
+		return nil, err
+	}
+	if viper.IsSet(prefix + "breaker_failures") {
+		pool.Failures = viper.GetInt(prefix + "breaker_failures")
+	}
+	if viper.IsSet(prefix + "breaker_cooldown") {
+		pool.Cooldown = viper.GetDuration(prefix + "breaker_cooldown")
+	}
+	return pool, nil
+}
+
+// newEndpoint creates an endpoint of a pool from its config entry
+func newEndpoint(entry any) (*ai.Endpoint, error) {
+	if name, ok := entry.(string); ok {
+		client, err := profileClient(name, false)
+		if err != nil {
+			return nil, err
+		}
+		return &ai.Endpoint{Name: name, Client: client}, nil
+	}
+
+	values, ok := entry.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a profile name or a map")
+	}
+	get := func(key string) string {
+		if value, ok := values[key]; ok && value != nil {
+			return secrets.Expand(fmt.Sprint(value))
+		}
+		return ""
+	}
+
+	profile := get("profile")
+	if err := checkProfile(profile); err != nil {
+		return nil, err
+	}
+
+	// The key of the endpoint replaces the one of the profile, so the apikey_cmd of
+	// the profile isn't run
+	var ref apiKeyRef
+	for _, kind := range []string{"apikey", "apikey_cmd", "apikey_file"} {
+		if value := get(kind); value != "" {
+			ref = apiKeyRef{Kind: kind, Value: value}
+			break
+		}
+	}
+
+	var client *ai.Client
+	var err error
+	if ref.Kind != "" {
+		client, err = keyedClient(profile, ref, false)
+	} else {
+		client, err = profileClient(profile, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if endpointURL := get("url"); endpointURL != "" {
+		client.URL = endpointURL
+	}
+	if model := get("model"); model != "" {
+		client.Model = model
+	}
+
+	weight := 0
+	if value := get("weight"); value != "" {
+		if weight, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("invalid weight %q", value)
+		}
+	}
+
+	name := get("name")
+	switch {
+	case name != "":
+	case profile != "" && get("url") == "" && get("model") == "":
+		name = profile
+	case profile != "":
+		name = profile + ":" + client.Model
+	default:
+		name = client.URL
+		if parsed, err := url.Parse(client.URL); err == nil && parsed.Host != "" {
+			name = parsed.Host
+		}
+		if client.Model != "" {
+			name += ":" + client.Model
+		}
+	}
+
+	return &ai.Endpoint{Name: name, Client: client, Weight: weight}, nil
+}
+
+// executorOptions returns the executor options derived from the config. The client is
+// used to embed semantic search queries.
+func executorOptions(client *ai.Client, unsafePaths bool) []executor.Options {
+	opts := []executor.Options{
+		executor.WithAllowedPaths(viper.GetStringSlice("allowed_paths")...),
+		executor.WithUnsafePaths(unsafePaths),
+		executor.WithCacheDir(viper.GetString("cache_dir")),
+	}
+	if client != nil {
+		opts = append(opts, executor.WithEmbedder(client))
+	}
+	return opts
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/bigjk/clai/runner"
+	"github.com/bigjk/clai/server"
+	"github.com/spf13/cobra"
+)
+
+// serverCmd creates a command serving the workflows of a directory with the handler
+// of the server. The token defaults to the environment variable tokenEnv.
+func serverCmd(use string, short string, defaultAddr string, tokenEnv string, handler func(s *server.Server) http.Handler) *cobra.Command {
+	var (
+		dir         string
+		addr        string
+		workingDir  string
+		token       string
+		concurrency int
+		unsafePaths bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if token == "" {
+				token = os.Getenv(tokenEnv)
+			}
+
+			s := &server.Server{
+				Dir:         dir,
+				WorkingDir:  workingDir,
+				Token:       token,
+				Concurrency: concurrency,
+				Clients:     newClient,
+				Options:     executorOptions(nil, unsafePaths),
+				Record: func(file string, content []byte, result *runner.Result) {
+					recordHistory(use, file, content, workingDir, []*runner.Result{result})
+				},
+			}
+
+			if token == "" {
+				fmt.Fprintln(os.Stderr, "Warning: no token set, everyone who can reach the server can run workflows")
+			}
+			fmt.Fprintf(os.Stderr, "Serving workflows of %s on %s\n", dir, addr)
+			return http.ListenAndServe(addr, handler(s))
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "./", "Directory containing the workflows")
+	cmd.Flags().StringVar(&addr, "addr", defaultAddr, "Address to listen on")
+	cmd.Flags().StringVar(&workingDir, "working_dir", "./", "Working directory for the workflows")
+	cmd.Flags().StringVar(&token, "token", "", fmt.Sprintf("Bearer token required for requests (default from %s)", tokenEnv))
+	cmd.Flags().IntVar(&concurrency, "concurrency", 4, "Maximum number of workflows running at the same time")
+	cmd.Flags().BoolVar(&unsafePaths, "unsafe-paths", false, "Allow helpers to read files outside of the working directory and allowed paths")
+	return cmd
+}
+
+func serveCmd() *cobra.Command {
+	return serverCmd("serve", "Serve the workflows of a directory as http api", "127.0.0.1:8080", "CLAI_SERVE_TOKEN", (*server.Server).Handler)
+}
+
+func proxyCmd() *cobra.Command {
+	return serverCmd("proxy", "Serve an OpenAI compatible api where the model clai/<name> applies a workflow", "127.0.0.1:8081", "CLAI_PROXY_TOKEN", (*server.Server).ProxyHandler)
+}
@@ -0,0 +1,151 @@
+package eval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bigjk/clai/postprocess"
+	"github.com/bigjk/clai/runner"
+	"gopkg.in/yaml.v3"
+)
+
+// Assertion checks the response of a case. Each assertion checks one thing, only the
+// length bounds can be combined.
+type Assertion struct {
+	Contains    string `yaml:"contains"`
+	NotContains string `yaml:"not_contains"`
+	Regex       string `yaml:"regex"`
+	// JSONSchema is an inline schema or the path of a json or yaml schema file
+	JSONSchema any  `yaml:"json_schema"`
+	MinLength  *int `yaml:"min_length"`
+	MaxLength  *int `yaml:"max_length"`
+	// Judge is a workflow that scores the response, available to it as .Candidates
+	Judge    string  `yaml:"judge"`
+	MinScore float64 `yaml:"min_score"`
+}
+
+func (a Assertion) validate() error {
+	kinds := 0
+	for _, set := range []bool{a.Contains != "", a.NotContains != "", a.Regex != "", a.JSONSchema != nil, a.MinLength != nil || a.MaxLength != nil, a.Judge != ""} {
+		if set {
+			kinds++
+		}
+	}
+
+	switch {
+	case kinds == 0:
+		return errors.New("assertion has no check, expected one of contains, not_contains, regex, json_schema, min_length, max_length or judge")
+	case kinds > 1:
+		return errors.New("assertion has multiple checks, use one assertion per check")
+	}
+
+	if a.Regex != "" {
+		if _, err := regexp.Compile(a.Regex); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// Check checks the assertion against a run and returns a description of the failure,
+// or an empty string if it holds. The runner is used to run the judge workflow.
+func (a Assertion) Check(run *runner.Result, r *runner.Runner) string {
+	output := run.Response
+
+	switch {
+	case a.Contains != "":
+		if !strings.Contains(output, a.Contains) {
+			return fmt.Sprintf("expected output to contain %q", a.Contains)
+		}
+	case a.NotContains != "":
+		if strings.Contains(output, a.NotContains) {
+			return fmt.Sprintf("expected output not to contain %q", a.NotContains)
+		}
+	case a.Regex != "":
+		if !regexp.MustCompile(a.Regex).MatchString(output) {
+			return fmt.Sprintf("expected output to match %q", a.Regex)
+		}
+	case a.JSONSchema != nil:
+		schema, err := loadSchema(a.JSONSchema)
+		if err != nil {
+			return err.Error()
+		}
+
+		data, err := parseJSONOutput(output)
+		if err != nil {
+			return err.Error()
+		}
+
+		if errs := ValidateSchema(schema, data); len(errs) > 0 {
+			return "output doesn't match the json schema: " + strings.Join(errs, "; ")
+		}
+	case a.MinLength != nil || a.MaxLength != nil:
+		length := utf8.RuneCountInString(output)
+		if a.MinLength != nil && length < *a.MinLength {
+			return fmt.Sprintf("expected output length >= %d, got %d", *a.MinLength, length)
+		}
+		if a.MaxLength != nil && length > *a.MaxLength {
+			return fmt.Sprintf("expected output length <= %d, got %d", *a.MaxLength, length)
+		}
+	case a.Judge != "":
+		workflow, err := loadWorkflow(a.Judge)
+		if err != nil {
+			return fmt.Sprintf("judge: %v", err)
+		}
+
+		judge := &runner.Runner{Workflow: workflow, Client: r.Client, WorkingDir: r.WorkingDir, Options: r.Options}
+		verdict, err := judge.Judge(run.Input, run.Seed, []runner.Candidate{{Index: 1, Seed: run.Seed, Content: output}})
+		if err != nil {
+			return err.Error()
+		}
+		if len(verdict.Scores) == 0 {
+			return "judge: no score for the output"
+		}
+		if score := verdict.Scores[0].Score; score < a.MinScore {
+			return fmt.Sprintf("expected judge score >= %g, got %g", a.MinScore, score)
+		}
+	}
+
+	return ""
+}
+
+// loadSchema returns an inline schema or reads a schema file
+func loadSchema(schema any) (any, error) {
+	file, ok := schema.(string)
+	if !ok {
+		return schema, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading json schema: %w", err)
+	}
+
+	var parsed any
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing json schema %s: %w", file, err)
+	}
+	return parsed, nil
+}
+
+// parseJSONOutput parses the output as json, or the first json value in it
+func parseJSONOutput(output string) (any, error) {
+	var data any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &data); err == nil {
+		return data, nil
+	}
+
+	raw, err := postprocess.ExtractJSON(output)
+	if err != nil {
+		return nil, errors.New("expected json output, found none")
+	}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return nil, fmt.Errorf("expected json output: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,149 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateSchema(t *testing.T) {
+	var schema any
+	require.NoError(t, yaml.Unmarshal([]byte(`
+type: object
+required: [name, cr]
+additionalProperties: false
+properties:
+  name: {type: string, minLength: 2, pattern: "^[A-Z]"}
+  cr: {type: integer, minimum: 0, maximum: 30}
+  type: {enum: [undead, dragon]}
+  tags: {type: array, maxItems: 2, items: {type: string}}
+`), &schema))
+
+	tests := []struct {
+		data string
+		errs int
+	}{
+		{data: `{"name": "Ghoul", "cr": 1, "type": "undead", "tags": ["a"]}`},
+		{data: `{"name": "ghoul", "cr": 1.5}`, errs: 2},
+		{data: `{"name": "G", "cr": 31, "extra": true}`, errs: 3},
+		{data: `{"cr": 1, "type": "fey", "tags": ["a", "b", 3]}`, errs: 4},
+		{data: `[]`, errs: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var data any
+			require.NoError(t, json.Unmarshal([]byte(tt.data), &data))
+			assert.Len(t, ValidateSchema(schema, data), tt.errs)
+		})
+	}
+
+	anyOf := map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "number"}}}
+	assert.Empty(t, ValidateSchema(anyOf, 1.0))
+	assert.NotEmpty(t, ValidateSchema(anyOf, true))
+}
+
+func TestSuite(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	write("monster.md", "# CLAI::USER\nMonster: {{ .Input }}")
+	write("stats.md", "# CLAI::USER\nStats for {{ .name }}")
+	write("judge.md", "# CLAI::USER\nJudge:{{ range .Candidates }} {{ . }}{{ end }}")
+	write("mock.yaml", `
+- match: "Judge: Monster: Ghoul"
+  content: "Candidate 1: 8"
+- match: "Judge:"
+  content: "Candidate 1: 2"
+- match: "Stats for Ghoul"
+  content: 'Here: {"name": "Ghoul", "cr": 1}'
+`)
+	write("suite.yaml", `
+name: monsters
+cases:
+  - name: echo
+    workflow: monster.md
+    input: Ghoul
+    assert:
+      - contains: Ghoul
+      - regex: "^Monster: [A-Z]"
+      - min_length: 5
+        max_length: 20
+      - judge: judge.md
+        min_score: 5
+  - name: json
+    workflow: stats.md
+    input: {name: Ghoul}
+    assert:
+      - json_schema: {type: object, required: [name, cr], properties: {cr: {type: integer}}}
+  - name: failing
+    workflow: monster.md
+    input: Lich
+    assert:
+      - not_contains: Lich
+      - max_length: 3
+      - judge: judge.md
+        min_score: 5
+  - name: missing
+    workflow: missing.md
+`)
+
+	suite, err := LoadSuite(filepath.Join(dir, "suite.yaml"))
+	require.NoError(t, err)
+
+	results := suite.Run(func(profile string) (*ai.Client, error) {
+		return ai.NewClient(ai.WithMock(filepath.Join(dir, "mock.yaml"))), nil
+	})
+	require.Len(t, results, 4)
+
+	assert.True(t, results[0].Passed(), results[0].Failures)
+	assert.True(t, results[1].Passed(), results[1].Failures)
+	assert.Len(t, results[2].Failures, 3)
+	assert.Error(t, results[3].Err)
+	assert.Equal(t, Summary{Passed: 2, Failed: 1, Errors: 1, Duration: Summarize(results).Duration}, Summarize(results))
+
+	var text bytes.Buffer
+	require.NoError(t, WriteText(&text, suite, results))
+	assert.Contains(t, text.String(), "PASS  echo")
+	assert.Contains(t, text.String(), "FAIL  failing")
+	assert.Contains(t, text.String(), "expected judge score >= 5, got 2")
+	assert.True(t, strings.HasSuffix(text.String(), "monsters: 2 passed, 1 failed, 1 errors\n"))
+
+	var junit bytes.Buffer
+	require.NoError(t, WriteJUnit(&junit, suite, results))
+
+	var report junitSuites
+	require.NoError(t, xml.Unmarshal(junit.Bytes(), &report))
+	require.Len(t, report.Suites, 1)
+	assert.Equal(t, 4, report.Suites[0].Tests)
+	assert.Equal(t, 1, report.Suites[0].Failures)
+	assert.Equal(t, 1, report.Suites[0].Errors)
+	assert.NotNil(t, report.Suites[0].Cases[2].Failure)
+}
+
+func TestLoadSuiteInvalid(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "suite.yaml")
+
+	for _, content := range []string{
+		"cases:\n  - input: x\n",
+		"cases:\n  - workflow: a.md\n    assert:\n      - {}\n",
+		"cases:\n  - workflow: a.md\n    assert:\n      - contains: a\n        regex: b\n",
+		"cases:\n  - workflow: a.md\n    assert:\n      - regex: '('\n",
+	} {
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+		_, err := LoadSuite(file)
+		assert.Error(t, err, content)
+	}
+}
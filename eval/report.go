@@ -0,0 +1,137 @@
+package eval
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Summary counts the results of a suite
+type Summary struct {
+	Passed   int
+	Failed   int
+	Errors   int
+	Duration time.Duration
+}
+
+// Summarize counts the passed, failed and errored cases
+func Summarize(results []*Result) Summary {
+	var summary Summary
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			summary.Errors++
+		case len(result.Failures) > 0:
+			summary.Failed++
+		default:
+			summary.Passed++
+		}
+		summary.Duration += result.Duration
+	}
+	return summary
+}
+
+// WriteText writes a human readable report
+func WriteText(w io.Writer, suite *Suite, results []*Result) error {
+	var out strings.Builder
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			out.WriteString(fmt.Sprintf("ERROR %s (%s)\n  %v\n", result.Case.Name, formatDuration(result.Duration), result.Err))
+		case len(result.Failures) > 0:
+			out.WriteString(fmt.Sprintf("FAIL  %s (%s)\n", result.Case.Name, formatDuration(result.Duration)))
+			for _, failure := range result.Failures {
+				out.WriteString(fmt.Sprintf("  - %s\n", failure))
+			}
+		default:
+			out.WriteString(fmt.Sprintf("PASS  %s (%s)\n", result.Case.Name, formatDuration(result.Duration)))
+		}
+	}
+
+	summary := Summarize(results)
+	out.WriteString(fmt.Sprintf("\n%s: %d passed, %d failed, %d errors\n", suite.Name, summary.Passed, summary.Failed, summary.Errors))
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes a JUnit XML report that CI systems can display
+func WriteJUnit(w io.Writer, suite *Suite, results []*Result) error {
+	summary := Summarize(results)
+	report := junitSuite{
+		Name:     suite.Name,
+		Tests:    len(results),
+		Failures: summary.Failed,
+		Errors:   summary.Errors,
+		Time:     junitTime(summary.Duration),
+	}
+
+	for _, result := range results {
+		c := junitCase{
+			Name:      result.Case.Name,
+			ClassName: suite.Name,
+			Time:      junitTime(result.Duration),
+		}
+		if result.Run != nil {
+			c.SystemOut = result.Run.Response
+		}
+
+		switch {
+		case result.Err != nil:
+			c.Error = &junitMessage{Message: result.Err.Error(), Text: result.Err.Error()}
+		case len(result.Failures) > 0:
+			c.Failure = &junitMessage{Message: result.Failures[0], Text: strings.Join(result.Failures, "\n")}
+		}
+		report.Cases = append(report.Cases, c)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitSuites{Suites: []junitSuite{report}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
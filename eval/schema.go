@@ -0,0 +1,218 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// ValidateSchema validates json data against a json schema and returns the violations.
+// The commonly used subset of the specification is supported: type, enum, const,
+// properties, required, additionalProperties, items, min/maxItems, min/maxLength,
+// pattern, minimum, maximum, anyOf, oneOf and allOf.
+func ValidateSchema(schema any, data any) []string {
+	var errs []string
+	validate(schema, data, "$", &errs)
+	return errs
+}
+
+func validate(schema any, data any, path string, errs *[]string) {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		// true or an empty schema allow everything, false nothing
+		if allowed, ok := schema.(bool); ok && !allowed {
+			*errs = append(*errs, fmt.Sprintf("%s: no value allowed", path))
+		}
+		return
+	}
+
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types, ok := s["type"]; ok && !matchesType(types, data) {
+		fail("expected type %v, got %s", types, jsonType(data))
+		return
+	}
+
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, value := range enum {
+			if jsonEqual(value, data) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value %v is not one of %v", data, enum)
+		}
+	}
+	if value, ok := s["const"]; ok && !jsonEqual(value, data) {
+		fail("expected %v, got %v", value, data)
+	}
+
+	switch value := data.(type) {
+	case map[string]any:
+		for _, key := range schemaStrings(s["required"]) {
+			if _, ok := value[key]; !ok {
+				fail("missing required property %q", key)
+			}
+		}
+
+		properties, _ := s["properties"].(map[string]any)
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if property, ok := properties[key]; ok {
+				validate(property, value[key], path+"."+key, errs)
+				continue
+			}
+			if additional, ok := s["additionalProperties"]; ok {
+				if allowed, ok := additional.(bool); ok && !allowed {
+					fail("unexpected property %q", key)
+				} else {
+					validate(additional, value[key], path+"."+key, errs)
+				}
+			}
+		}
+	case []any:
+		if min, ok := schemaNumber(s["minItems"]); ok && float64(len(value)) < min {
+			fail("expected at least %g items, got %d", min, len(value))
+		}
+		if max, ok := schemaNumber(s["maxItems"]); ok && float64(len(value)) > max {
+			fail("expected at most %g items, got %d", max, len(value))
+		}
+		if items, ok := s["items"]; ok {
+			for i, item := range value {
+				validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(value))
+		if min, ok := schemaNumber(s["minLength"]); ok && length < min {
+			fail("expected at least %g characters, got %g", min, length)
+		}
+		if max, ok := schemaNumber(s["maxLength"]); ok && length > max {
+			fail("expected at most %g characters, got %g", max, length)
+		}
+		if pattern, ok := s["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				fail("invalid pattern %q: %v", pattern, err)
+			} else if !re.MatchString(value) {
+				fail("%q doesn't match pattern %q", value, pattern)
+			}
+		}
+	case float64:
+		if min, ok := schemaNumber(s["minimum"]); ok && value < min {
+			fail("expected a value >= %g, got %g", min, value)
+		}
+		if max, ok := schemaNumber(s["maximum"]); ok && value > max {
+			fail("expected a value <= %g, got %g", max, value)
+		}
+	}
+
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			validate(sub, data, path, errs)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok && countMatches(anyOf, data, path) == 0 {
+		fail("value doesn't match any of the anyOf schemas")
+	}
+	if one, ok := s["oneOf"].([]any); ok {
+		if n := countMatches(one, data, path); n != 1 {
+			fail("value matches %d of the oneOf schemas, expected exactly one", n)
+		}
+	}
+}
+
+// countMatches returns the number of schemas the data is valid against
+func countMatches(schemas []any, data any, path string) int {
+	matches := 0
+	for _, sub := range schemas {
+		var subErrs []string
+		validate(sub, data, path, &subErrs)
+		if len(subErrs) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+func matchesType(types any, data any) bool {
+	for _, t := range schemaStrings(types) {
+		actual := jsonType(data)
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the json schema type name of a decoded json value
+func jsonType(data any) string {
+	switch value := data.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", data)
+}
+
+// schemaStrings returns a string or a list of strings of a schema keyword
+func schemaStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// schemaNumber returns a numeric schema keyword. Schemas parsed from yaml contain ints.
+func schemaNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// jsonEqual compares schema values with json values, ignoring the difference between
+// yaml ints and json floats
+func jsonEqual(a, b any) bool {
+	if x, ok := schemaNumber(a); ok {
+		y, ok := schemaNumber(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,213 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/executor"
+	"github.com/bigjk/clai/runner"
+	"github.com/bigjk/clai/templating"
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a set of workflow test cases
+type Suite struct {
+	Name        string `yaml:"name"`
+	Concurrency int    `yaml:"concurrency"`
+	Cases       []Case `yaml:"cases"`
+
+	// Dir is the directory of the suite file, relative paths are resolved against it
+	Dir string `yaml:"-"`
+}
+
+// Case runs a workflow with an input and checks the response with assertions. The
+// input can be a string or an object that is passed as json.
+type Case struct {
+	Name       string      `yaml:"name"`
+	Workflow   string      `yaml:"workflow"`
+	Input      any         `yaml:"input"`
+	Seed       int64       `yaml:"seed"`
+	Profile    string      `yaml:"profile"`
+	Model      string      `yaml:"model"`
+	WorkingDir string      `yaml:"working_dir"`
+	Assert     []Assertion `yaml:"assert"`
+}
+
+// Result is the outcome of a case. Failures are assertions that didn't hold, Err is
+// set if the case couldn't be run at all.
+type Result struct {
+	Case     Case
+	Run      *runner.Result
+	Failures []string
+	Err      error
+	Duration time.Duration
+}
+
+// Passed checks if the case ran and all assertions held
+func (r *Result) Passed() bool {
+	return r.Err == nil && len(r.Failures) == 0
+}
+
+// LoadSuite reads a suite from a yaml file
+func LoadSuite(file string) (*Suite, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading suite: %w", err)
+	}
+
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("error parsing suite %s: %w", file, err)
+	}
+
+	if suite.Name == "" {
+		suite.Name = filepath.Base(file)
+	}
+	suite.Dir = filepath.Dir(file)
+
+	for i, c := range suite.Cases {
+		if c.Workflow == "" {
+			return nil, fmt.Errorf("case %d of %s has no workflow", i+1, file)
+		}
+		if c.Name == "" {
+			suite.Cases[i].Name = fmt.Sprintf("case_%d", i+1)
+		}
+		for j, assertion := range c.Assert {
+			if err := assertion.validate(); err != nil {
+				return nil, fmt.Errorf("case %q assertion %d: %w", suite.Cases[i].Name, j+1, err)
+			}
+		}
+	}
+
+	return &suite, nil
+}
+
+// path resolves a path of the suite relative to its directory
+func (s *Suite) path(file string) string {
+	if file == "" || filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(s.Dir, file)
+}
+
+// ClientFactory creates the client for a profile. The profile is the one requested by
+// the case or its workflow and can be empty.
+type ClientFactory func(profile string) (*ai.Client, error)
+
+// Run runs all cases concurrently with clients of the factory. The results are in the
+// order of the cases.
+func (s *Suite) Run(clients ClientFactory, opts ...executor.Options) []*Result {
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	results := make([]*Result, len(s.Cases))
+	sem := make(chan struct{}, concurrency)
+	wg := &sync.WaitGroup{}
+	wg.Add(len(s.Cases))
+
+	for i := range s.Cases {
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			results[i] = s.runCase(s.Cases[i], clients, opts)
+			results[i].Duration = time.Since(start)
+		}(i)
+	}
+
+	wg.Wait()
+	return results
+}
+
+func (s *Suite) runCase(c Case, clients ClientFactory, opts []executor.Options) *Result {
+	result := &Result{Case: c}
+
+	input, err := caseInput(c.Input)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	workflow, err := loadWorkflow(s.path(c.Workflow))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	profile := c.Profile
+	if profile == "" {
+		profile = workflow.Meta.Profile
+	}
+
+	client, err := clients(profile)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if c.Model != "" {
+		if err := client.SetModel(c.Model); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	workingDir := s.path(c.WorkingDir)
+	if workingDir == "" {
+		workingDir = s.Dir
+	}
+
+	r := &runner.Runner{Workflow: workflow, Client: client, WorkingDir: workingDir, Options: append(opts[:len(opts):len(opts)], executor.WithEmbedder(client))}
+	result.Run, err = r.Run(input, c.Seed)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	for _, assertion := range c.Assert {
+		if assertion.Judge != "" {
+			assertion.Judge = s.path(assertion.Judge)
+		}
+		if path, ok := assertion.JSONSchema.(string); ok {
+			assertion.JSONSchema = s.path(path)
+		}
+
+		if failure := assertion.Check(result.Run, r); failure != "" {
+			result.Failures = append(result.Failures, failure)
+		}
+	}
+
+	return result
+}
+
+// caseInput converts the input of a case to the input string of a workflow
+func caseInput(input any) (string, error) {
+	switch value := input.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("error encoding input: %w", err)
+	}
+	return string(data), nil
+}
+
+func loadWorkflow(file string) (*templating.Workflow, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading workflow: %w", err)
+	}
+	return templating.ParseWorkflowFile(file, string(content))
+}
@@ -0,0 +1,10 @@
+// Package examples contains the example workflows, which are also the templates of
+// clai new.
+package examples
+
+import "embed"
+
+// Templates are the example workflows
+//
+//go:embed *.md
+var Templates embed.FS
@@ -0,0 +1,307 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CSV reads a csv file with a header row and returns one map per row
+func (e *Env) CSV(file string) []map[string]any {
+	content, err := e.readFile(file)
+	if err != nil {
+		panic(err)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		panic(fmt.Errorf("error parsing %s: %w", file, err))
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]any, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := map[string]any{}
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = record[i]
+			} else {
+				row[name] = ""
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// JSON reads a json file
+func (e *Env) JSON(file string) any {
+	content, err := e.readFile(file)
+	if err != nil {
+		panic(err)
+	}
+
+	var data any
+	if err := json.Unmarshal(content, &data); err != nil {
+		panic(fmt.Errorf("error parsing %s: %w", file, err))
+	}
+	return data
+}
+
+// JSONL reads a file containing one json value per line
+func (e *Env) JSONL(file string) []any {
+	content, err := e.readFile(file)
+	if err != nil {
+		panic(err)
+	}
+
+	var rows []any
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var row any
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			panic(fmt.Errorf("error parsing %s line %d: %w", file, line, err))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// YAML reads a yaml file
+func (e *Env) YAML(file string) any {
+	content, err := e.readFile(file)
+	if err != nil {
+		panic(err)
+	}
+
+	var data any
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		panic(fmt.Errorf("error parsing %s: %w", file, err))
+	}
+	return data
+}
+
+// SampleRows returns count random elements of a list
+func (e *Env) SampleRows(rows any, count int) []any {
+	list := toList(rows)
+	if count > len(list) {
+		count = len(list)
+	}
+
+	e.rng.Shuffle(len(list), func(i, j int) {
+		list[i], list[j] = list[j], list[i]
+	})
+
+	return list[:count]
+}
+
+// Where returns all elements of a list that match the filter expression
+func Where(rows any, expr string) []any {
+	parsed, err := ParseExpr(expr)
+	if err != nil {
+		panic(err)
+	}
+
+	var result []any
+	for _, row := range toList(rows) {
+		if parsed.Match(row) {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+// Query returns the value at a JSONPath like path, e.g. "$.items[0].name" or
+// "items[*].name". Wildcards return a list of all matches.
+func Query(data any, path string) any {
+	return lookupPath(data, path)
+}
+
+// Table renders a list of maps as a markdown table. If no columns are given all
+// keys are used in alphabetical order.
+func Table(rows any, columns ...string) string {
+	list := toList(rows)
+
+	if len(columns) == 0 {
+		seen := map[string]bool{}
+		for _, row := range list {
+			for _, key := range mapKeys(row) {
+				if !seen[key] {
+					seen[key] = true
+					columns = append(columns, key)
+				}
+			}
+		}
+		sort.Strings(columns)
+	}
+
+	var result strings.Builder
+	result.WriteString("| " + strings.Join(columns, " | ") + " |\n")
+	result.WriteString("|" + strings.Repeat(" --- |", len(columns)) + "\n")
+	for _, row := range list {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			value := lookupPath(row, column)
+			if value != nil {
+				cells[i] = formatCell(value)
+			}
+		}
+		result.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+
+	return result.String()
+}
+
+// ToJSON renders a value as indented json
+func ToJSON(data any) string {
+	out, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(out)
+}
+
+func formatCell(value any) string {
+	var cell string
+	switch value.(type) {
+	case map[string]any, []any:
+		out, _ := json.Marshal(value)
+		cell = string(out)
+	default:
+		cell = fmt.Sprint(value)
+	}
+
+	cell = strings.ReplaceAll(cell, "|", "\\|")
+	return strings.ReplaceAll(cell, "\n", " ")
+}
+
+// toList converts any slice or array to []any. Other values are wrapped in a list.
+func toList(value any) []any {
+	if value == nil {
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []any{value}
+	}
+
+	list := make([]any, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list
+}
+
+// mapKeys returns the keys of a map with string keys
+func mapKeys(value any) []string {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map {
+		return nil
+	}
+
+	var keys []string
+	for _, key := range rv.MapKeys() {
+		keys = append(keys, fmt.Sprint(key.Interface()))
+	}
+	return keys
+}
+
+// lookupPath resolves a path like "a.b[0].c" or "a[*].b" in nested maps and lists
+func lookupPath(data any, path string) any {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return data
+	}
+
+	values := []any{data}
+	wildcard := false
+	for _, step := range splitPath(path) {
+		var next []any
+		for _, value := range values {
+			switch {
+			case step == "[*]" || step == "[]":
+				wildcard = true
+				next = append(next, toList(value)...)
+			case strings.HasPrefix(step, "["):
+				index, err := strconv.Atoi(strings.Trim(step, "[]"))
+				list := toList(value)
+				if err != nil || value == nil {
+					continue
+				}
+				if index < 0 {
+					index += len(list)
+				}
+				if index >= 0 && index < len(list) {
+					next = append(next, list[index])
+				}
+			default:
+				rv := reflect.ValueOf(value)
+				if rv.Kind() != reflect.Map {
+					continue
+				}
+				for _, key := range rv.MapKeys() {
+					if fmt.Sprint(key.Interface()) == step {
+						next = append(next, rv.MapIndex(key).Interface())
+						break
+					}
+				}
+			}
+		}
+		values = next
+	}
+
+	if wildcard {
+		return values
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// splitPath splits "a.b[0]" into "a", "b" and "[0]"
+func splitPath(path string) []string {
+	var steps []string
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			start := strings.Index(part, "[")
+			if start < 0 {
+				steps = append(steps, part)
+				break
+			}
+			if start > 0 {
+				steps = append(steps, part[:start])
+			}
+
+			end := strings.Index(part[start:], "]")
+			if end < 0 {
+				steps = append(steps, part[start:])
+				break
+			}
+			steps = append(steps, part[start:start+end+1])
+			part = part[start+end+1:]
+		}
+	}
+	return steps
+}
@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpr(t *testing.T) {
+	data := map[string]any{
+		"name": "Red Dragon",
+		"type": "dragon",
+		"cr":   "17",
+		"hp":   256.0,
+		"tags": []any{"fire", "flying"},
+		"stats": map[string]any{
+			"str": 27.0,
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: "cr >= 5 && type == 'dragon'", want: true},
+		{expr: "cr > 20 || type == \"undead\"", want: false},
+		{expr: "cr < 100", want: true},
+		{expr: "hp == 256", want: true},
+		{expr: "stats.str > 20", want: true},
+		{expr: "'fire' in tags", want: true},
+		{expr: "tags contains 'cold'", want: false},
+		{expr: "name contains 'Dragon'", want: true},
+		{expr: "name =~ '^Red'", want: true},
+		{expr: "!(type == 'dragon')", want: false},
+		{expr: "missing == null", want: true},
+		{expr: "missing > 1", want: false},
+		{expr: "tags", want: true},
+		{expr: "not missing and cr != 5", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseExpr(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, expr.Match(data))
+		})
+	}
+
+	for _, invalid := range []string{"cr >=", "(cr > 1", "name == 'open", "cr # 1"} {
+		_, err := ParseExpr(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	data := map[string]any{
+		"items": []any{
+			map[string]any{"name": "Sword", "price": 10.0},
+			map[string]any{"name": "Shield", "price": 5.0},
+		},
+	}
+
+	assert.Equal(t, "Sword", Query(data, "$.items[0].name"))
+	assert.Equal(t, "Shield", Query(data, "items[-1].name"))
+	assert.Equal(t, []any{"Sword", "Shield"}, Query(data, ".items[*].name"))
+	assert.Nil(t, Query(data, "items[5].name"))
+}
+
+func TestCSVWhereTable(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "items.csv"), []byte("name,price,rarity\nSword,10,common\nWand | of Fire,250,rare\nShield,5,common\n"), 0644))
+
+	env, err := NewEnv(root, nil, false)
+	require.NoError(t, err)
+
+	rows := env.CSV(filepath.Join(root, "items.csv"))
+	require.Len(t, rows, 3)
+	assert.Equal(t, "Sword", rows[0]["name"])
+
+	expensive := Where(rows, "price > 9")
+	assert.Len(t, expensive, 2)
+	assert.Len(t, env.SampleRows(rows, 2), 2)
+	assert.Len(t, env.SampleRows(rows, 10), 3)
+
+	assert.Equal(t, "| name | price |\n| --- | --- |\n| Sword | 10 |\n| Wand \\| of Fire | 250 |\n", Table(expensive, "name", "price"))
+	assert.Equal(t, "| name | price | rarity |\n| --- | --- | --- |\n| Shield | 5 | common |\n", Table(Where(rows, "price < 9")))
+}
@@ -0,0 +1,146 @@
+package executor
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bigjk/clai/index"
+)
+
+// Env is the environment the template helpers of a single execution run in.
+// It confines all file access to the working directory and the allowed paths.
+type Env struct {
+	Root     string
+	CacheDir string
+	Embedder index.Embedder
+
+	allowed []string
+	unsafe  bool
+	notes   *vault
+	rng     *rand.Rand
+	// nonce is part of the message separators and image placeholders
+	nonce string
+}
+
+// NewEnv creates a new environment rooted at the given directory. Additional
+// directories can be made readable with allowed. If unsafe is set, no path
+// confinement is applied at all.
+func NewEnv(root string, allowed []string, unsafe bool) (*Env, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// The root is compared with resolved paths, so it is resolved as well
+	absRoot, err = resolveSymlinks(absRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &Env{
+		Root:   absRoot,
+		unsafe: unsafe,
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		nonce:  newNonce(),
+	}
+
+	for _, dir := range append([]string{absRoot}, allowed...) {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(absRoot, dir)
+		}
+
+		// Resolve symlinks so that the checks compare real locations. Allowed
+		// paths that don't exist (yet) are kept as is.
+		if resolved, err := resolveSymlinks(dir); err == nil {
+			dir = resolved
+		}
+
+		env.allowed = append(env.allowed, filepath.Clean(dir))
+	}
+
+	return env, nil
+}
+
+// Seed makes the random sampling of the helpers reproducible
+func (e *Env) Seed(seed int64) {
+	e.rng = rand.New(rand.NewSource(seed))
+}
+
+// Resolve returns the absolute path for a path given to a helper. Relative paths
+// are relative to the root. An error is returned if the path, after resolving
+// all symlinks, is outside the allowed paths.
+func (e *Env) Resolve(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.Root, path)
+	}
+	path = filepath.Clean(path)
+
+	if err := e.Check(path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// Check returns an error if the path, after resolving all symlinks, is outside the
+// allowed paths.
+func (e *Env) Check(path string) error {
+	if e.unsafe {
+		return nil
+	}
+
+	// Missing files are left to the helper to report, but their parents are still
+	// resolved, so they can't escape through a symlinked directory
+	resolved, err := resolveSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range e.allowed {
+		if isWithin(dir, resolved) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("access to %q denied: path is outside of the working directory %q and the allowed paths (add it to allowed_paths or use --unsafe-paths)", path, e.Root)
+}
+
+// resolveSymlinks resolves the symlinks of the path. If the path doesn't exist, the
+// symlinks of its longest existing parent are resolved.
+func resolveSymlinks(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil || !os.IsNotExist(err) {
+		return resolved, err
+	}
+
+	parent := filepath.Dir(path)
+	if parent == path {
+		return filepath.Clean(path), nil
+	}
+	resolvedParent, err := resolveSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolvedParent, filepath.Base(path)), nil
+}
+
+// readFile reads a file after checking that it may be accessed.
+func (e *Env) readFile(path string) ([]byte, error) {
+	if err := e.Check(path); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// isWithin checks if path is dir or inside of dir.
+func isWithin(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvResolve(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	allowed := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "inside.txt"), []byte("inside"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(allowed, "shared.txt"), []byte("shared"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "link.txt")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "linkdir")))
+
+	env, err := NewEnv(root, []string{allowed}, false)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "relative inside", path: "inside.txt"},
+		{name: "missing inside", path: "missing.txt"},
+		{name: "parent traversal", path: "../" + filepath.Base(outside) + "/secret.txt", wantErr: true},
+		{name: "absolute outside", path: filepath.Join(outside, "secret.txt"), wantErr: true},
+		{name: "symlinked file", path: "link.txt", wantErr: true},
+		{name: "symlinked dir", path: "linkdir/secret.txt", wantErr: true},
+		{name: "missing in symlinked dir", path: "linkdir/missing.txt", wantErr: true},
+		{name: "allowed path", path: filepath.Join(allowed, "shared.txt")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := env.Resolve(tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	unsafeEnv, err := NewEnv(root, nil, true)
+	require.NoError(t, err)
+	_, err = unsafeEnv.Resolve("link.txt")
+	assert.NoError(t, err)
+}
+
+func TestSampleFilesSkipsEscapingSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("inside"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "b.txt")))
+
+	env, err := NewEnv(root, nil, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "inside\n\n", env.SampleFiles(root, 10, false))
+	assert.Equal(t, "inside\n\n", env.SampleFilesDeep(root, 1, false))
+}
+
+func TestEnvSymlinkedRoot(t *testing.T) {
+	real := t.TempDir()
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Symlink(real, link))
+	require.NoError(t, os.WriteFile(filepath.Join(real, "inside.txt"), []byte("inside"), 0644))
+
+	env, err := NewEnv(link, nil, false)
+	require.NoError(t, err)
+
+	for _, path := range []string{"inside.txt", "missing.txt", filepath.Join(link, "missing.txt")} {
+		_, err := env.Resolve(path)
+		assert.NoError(t, err, path)
+	}
+}
+
+func TestExecuteRunCommand(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	for _, dir := range []string{root, outside} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "hello.sh"), []byte("#!/bin/sh\necho hello\n"), 0755))
+	}
+
+	res, err := Execute([]ai.Message{{Role: "user", Content: `{{ call .RunCommand "./hello.sh" }}`}}, "", root)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", res[0].Content)
+
+	_, err = Execute([]ai.Message{{Role: "user", Content: `{{ call .RunCommand "../` + filepath.Base(outside) + `/hello.sh" }}`}}, "", root)
+	assert.ErrorContains(t, err, "denied")
+}
@@ -2,65 +2,389 @@ package executor
 
 import (
 	"encoding/json"
+	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/index"
 	"github.com/bigjk/clai/templating"
 )
 
-func Execute(messages []ai.Message, userInput string, rootDir string) ([]ai.Message, error) {
+type Options func(*config)
+
+type config struct {
+	allowedPaths []string
+	unsafePaths  bool
+	cacheDir     string
+	embedder     index.Embedder
+	seed         *int64
+	data         map[string]any
+}
+
+// WithAllowedPaths allows the helpers to read from the given paths in addition
+// to the working directory
+func WithAllowedPaths(paths ...string) Options {
+	return func(c *config) {
+		c.allowedPaths = append(c.allowedPaths, paths...)
+	}
+}
+
+// WithUnsafePaths disables the path confinement of the helpers
+func WithUnsafePaths(unsafe bool) Options {
+	return func(c *config) {
+		c.unsafePaths = unsafe
+	}
+}
+
+// WithCacheDir sets the directory search indexes are cached in. By default the
+// user cache directory is used.
+func WithCacheDir(dir string) Options {
+	return func(c *config) {
+		c.cacheDir = dir
+	}
+}
+
+// WithEmbedder sets the embedder used for semantic search
+func WithEmbedder(embedder index.Embedder) Options {
+	return func(c *config) {
+		c.embedder = embedder
+	}
+}
+
+// WithSeed sets the seed of the random sampling, so that the same seed selects the same
+// files, lines and rows again
+func WithSeed(seed int64) Options {
+	return func(c *config) {
+		c.seed = &seed
+	}
+}
+
+// WithData adds values to the template data, in addition to the fields of the input
+func WithData(data map[string]any) Options {
+	return func(c *config) {
+		if c.data == nil {
+			c.data = map[string]any{}
+		}
+		for key, value := range data {
+			c.data[key] = value
+		}
+	}
+}
+
+// DefaultCacheDir returns the directory search indexes are cached in if none is configured
+func DefaultCacheDir() string {
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(userCache, "clai")
+}
+
+// MessageError is an error rendering one of the messages
+type MessageError struct {
+	// Index is the index of the message
+	Index int
+	// Line is the line in the content of the message, 0 if unknown
+	Line int
+	Err  error
+}
+
+// Error returns the error without the position in the joined template
+func (e *MessageError) Error() string {
+	return templateLocation.ReplaceAllString(e.Err.Error(), "")
+}
+
+func (e *MessageError) Unwrap() error {
+	return e.Err
+}
+
+func Execute(messages []ai.Message, userInput string, rootDir string, opts ...Options) ([]ai.Message, error) {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	env, err := NewEnv(rootDir, cfg.allowedPaths, cfg.unsafePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	env.CacheDir = cfg.cacheDir
+	if env.CacheDir == "" {
+		env.CacheDir = DefaultCacheDir()
+	}
+	env.Embedder = cfg.embedder
+	if cfg.seed != nil {
+		env.Seed(*cfg.seed)
+	}
+
+	// resolve maps a path given to a helper to an absolute path and panics if it
+	// is not allowed. The panic is turned into a template error.
+	resolve := func(path string) string {
+		resolved, err := env.Resolve(path)
+		if err != nil {
+			panic(err)
+		}
+		return resolved
+	}
+
 	var data map[string]any
 
-	err := json.Unmarshal([]byte(userInput), &data)
+	err = json.Unmarshal([]byte(userInput), &data)
 	if err != nil {
 		data = map[string]any{"Input": userInput}
 	}
+	for key, value := range cfg.data {
+		data[key] = value
+	}
+
+	// Images are attached to the message the placeholder returned by attach ends up in
+	var attachments []ai.Image
+	attach := func(image ai.Image) string {
+		attachments = append(attachments, image)
+		return fmt.Sprintf(imagePlaceholder, env.nonce, len(attachments)-1)
+	}
+	for name, f := range helperFuncs(env, resolve, attach) {
+		data[name] = f
+	}
+
+	// The messages are rendered as one template, so actions like if and range can
+	// span several messages. Each message starts with a separator line, which is
+	// repeated or left out with the content that follows it.
+	document, starts := joinMessages(messages, env.nonce)
+	res, err := templating.ExecuteTemplate(document, data)
+	if err != nil {
+		return nil, newMessageError(err, starts)
+	}
+
+	parts, err := splitMessages(res, env.nonce, len(messages))
+	if err != nil {
+		return nil, err
+	}
+
+	placeholders := imagePlaceholderPattern(env.nonce)
+	var newMessages []ai.Message
+	for _, part := range parts {
+		msg := ai.Message{Role: part.role}
+		switch {
+		case part.resumed:
+			// The attachments and the cache breakpoint belong to the first part
+			msg = ai.Message{Role: messages[part.index].Role, Name: messages[part.index].Name}
+		case part.index >= 0:
+			msg = messages[part.index]
+		}
+
+		// Copy the images, the messages are shared between concurrent runs
+		var images []ai.Image
+		images = append(images, msg.Images...)
+
+		var invalid error
+		content := placeholders.ReplaceAllStringFunc(part.content, func(placeholder string) string {
+			i, err := strconv.Atoi(placeholders.FindStringSubmatch(placeholder)[1])
+			if err != nil || i >= len(attachments) {
+				invalid = fmt.Errorf("invalid image placeholder %q", placeholder)
+				return ""
+			}
+			images = append(images, attachments[i])
+			return ""
+		})
+		if invalid != nil {
+			return nil, &MessageError{Index: max(part.index, 0), Err: invalid}
+		}
+		content = strings.TrimSpace(content)
+
+		files, err := attachFiles(env, resolve, msg.Attachments)
+		if err != nil {
+			return nil, &MessageError{Index: max(part.index, 0), Err: err}
+		}
+		images = append(images, files...)
+
+		// Messages that are empty after rendering are dropped
+		if content == "" && len(images) == 0 {
+			continue
+		}
+
+		newMessages = append(newMessages, ai.Message{
+			Role:    msg.Role,
+			Content: content,
+			Images:  images,
+			Name:    msg.Name,
+			Cache:   msg.Cache,
+		})
+	}
+
+	return newMessages, nil
+}
+
+// attachFiles reads the attachments of a message like the Image helper
+func attachFiles(env *Env, resolve func(string) string, files []string) (images []ai.Image, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("error attaching file: %v", r)
+		}
+	}()
+
+	for _, file := range files {
+		images = append(images, env.Image(resolve(file), 0))
+	}
+	return images, nil
+}
 
+// helperFuncs returns the helpers available in the templates by name. resolve maps a
+// path to an allowed absolute path, attach attaches an image to the message the
+// returned placeholder is rendered into.
+func helperFuncs(env *Env, resolve func(string) string, attach func(ai.Image) string) map[string]any {
+	funcs := map[string]any{}
 	registerFunc := func(names []string, f any) {
 		for _, name := range names {
-			data[name] = f
+			funcs[name] = f
 		}
 	}
 
+	registerFunc([]string{"FewShot"}, func(folder string, count int, field ...string) string {
+		if len(field) > 0 {
+			return env.FewShot(resolve(folder), count, field[0])
+		}
+		return env.FewShot(resolve(folder), count, "")
+	})
+	registerFunc([]string{"Examples"}, func(folder string, count int, field ...string) []Example {
+		if len(field) > 0 {
+			return env.SampleExamples(resolve(folder), count, field[0])
+		}
+		return env.SampleExamples(resolve(folder), count, "")
+	})
 	registerFunc([]string{"SampleFiles", "SF"}, func(folder string, count int, meta bool) string {
-		return SampleFiles(filepath.Join(rootDir, folder), count, meta)
+		return env.SampleFiles(resolve(folder), count, meta)
 	})
 	registerFunc([]string{"SampleFilesDeep", "SFD"}, func(folder string, count int, meta bool) string {
-		return SampleFilesDeep(filepath.Join(rootDir, folder), count, meta)
+		return env.SampleFilesDeep(resolve(folder), count, meta)
 	})
 	registerFunc([]string{"SampleFilesPattern", "SFP"}, func(folder string, pattern string, count int, meta bool) string {
-		return SampleFilesPattern(filepath.Join(rootDir, folder), pattern, count, meta)
+		return env.SampleFilesPattern(resolve(folder), pattern, count, meta)
 	})
 	registerFunc([]string{"SampleFilesPatternDeep", "SFDP"}, func(folder string, pattern string, count int, meta bool) string {
-		return SampleFilesPatternDeep(filepath.Join(rootDir, folder), pattern, count, meta)
+		return env.SampleFilesPatternDeep(resolve(folder), pattern, count, meta)
+	})
+	registerFunc([]string{"SampleFilesWhere", "SFW"}, func(folder string, expr string, count int, meta bool, fields ...string) string {
+		return env.SampleFilesWhere(resolve(folder), expr, count, meta, fields...)
+	})
+	registerFunc([]string{"SampleFilesWhereDeep", "SFWD"}, func(folder string, expr string, count int, meta bool, fields ...string) string {
+		return env.SampleFilesWhereDeep(resolve(folder), expr, count, meta, fields...)
+	})
+	registerFunc([]string{"Frontmatter", "FM"}, func(file string) map[string]any {
+		return env.Frontmatter(resolve(file))
 	})
 	registerFunc([]string{"SampleLines", "SL"}, func(file string, count int) string {
-		return SampleLines(filepath.Join(rootDir, file), count)
+		return env.SampleLines(resolve(file), count)
 	})
 	registerFunc([]string{"File", "F"}, func(file string) string {
-		return File(filepath.Join(rootDir, file))
+		return env.File(resolve(file))
 	})
 	registerFunc([]string{"SampleChunk", "SC"}, func(file string, count int) string {
-		return SampleChunk(filepath.Join(rootDir, file), count)
+		return env.SampleChunk(resolve(file), count)
+	})
+	registerFunc([]string{"Glob", "GL"}, func(pattern string, exclude ...string) []string {
+		return env.Glob(pattern, exclude...)
+	})
+	registerFunc([]string{"Files", "FS"}, func(pattern string, meta bool, exclude ...string) string {
+		return env.Files(pattern, meta, exclude...)
+	})
+	registerFunc([]string{"SampleGlob", "SG"}, func(pattern string, count int, meta bool, exclude ...string) string {
+		return env.SampleGlob(pattern, count, meta, exclude...)
+	})
+	registerFunc([]string{"Note"}, func(target string, depth ...int) string {
+		if len(depth) > 0 {
+			return env.Note(target, depth[0])
+		}
+		return env.Note(target, 0)
+	})
+	registerFunc([]string{"Tagged"}, env.Tagged)
+	registerFunc([]string{"SampleTag", "ST"}, env.SampleTag)
+	registerFunc([]string{"Backlinks"}, env.Backlinks)
+	registerFunc([]string{"StripObsidian"}, StripObsidian)
+	registerFunc([]string{"Relevant", "R"}, func(folder string, query string, count int, meta bool) string {
+		return env.Relevant(resolve(folder), query, count, meta)
+	})
+	registerFunc([]string{"RelevantMix", "RM"}, func(folder string, query string, relevant int, random int, meta bool) string {
+		return env.RelevantMix(resolve(folder), query, relevant, random, meta)
+	})
+	registerFunc([]string{"Semantic"}, func(folder string, query string, k int) string {
+		return env.Semantic(resolve(folder), query, k)
+	})
+	registerFunc([]string{"CSV"}, func(file string) []map[string]any {
+		return env.CSV(resolve(file))
+	})
+	registerFunc([]string{"JSON"}, func(file string) any {
+		return env.JSON(resolve(file))
+	})
+	registerFunc([]string{"JSONL"}, func(file string) []any {
+		return env.JSONL(resolve(file))
+	})
+	registerFunc([]string{"YAML"}, func(file string) any {
+		return env.YAML(resolve(file))
+	})
+	registerFunc([]string{"SampleRows"}, env.SampleRows)
+	registerFunc([]string{"Where"}, Where)
+	registerFunc([]string{"Query"}, Query)
+	registerFunc([]string{"Table"}, Table)
+	registerFunc([]string{"ToJSON"}, ToJSON)
+	registerFunc([]string{"Image", "IMG"}, func(file string, maxSize ...int) string {
+		size := 0
+		if len(maxSize) > 0 {
+			size = maxSize[0]
+		}
+		return attach(env.Image(resolve(file), size))
 	})
 	registerFunc([]string{"RunCommand", "RC"}, func(command string, args ...string) string {
-		if command[0] == '.' {
-			command = filepath.Join(rootDir, command[1:])
+		// Only commands of the working directory are checked, what the command
+		// itself accesses isn't confined
+		if strings.HasPrefix(command, ".") {
+			command = resolve(command)
 		}
 		return RunCommand(command, args...)
 	})
 
-	var newMessages []ai.Message
-	for i := range messages {
-		res, err := templating.ExecuteTemplate(messages[i].Content, data)
-		if err != nil {
-			return nil, err
-		}
-		newMessages = append(newMessages, ai.Message{
-			Role:    messages[i].Role,
-			Content: res,
-		})
+	return funcs
+}
+
+// helperPaths are the helpers whose first argument is a file or a folder
+var helperPaths = map[string]string{
+	"File": "file", "F": "file",
+	"SampleLines": "file", "SL": "file",
+	"SampleChunk": "file", "SC": "file",
+	"Frontmatter": "file", "FM": "file",
+	"CSV": "file", "JSON": "file", "JSONL": "file", "YAML": "file",
+	"Image": "file", "IMG": "file",
+	"FewShot": "folder", "Examples": "folder",
+	"SampleFiles": "folder", "SF": "folder",
+	"SampleFilesDeep": "folder", "SFD": "folder",
+	"SampleFilesPattern": "folder", "SFP": "folder",
+	"SampleFilesPatternDeep": "folder", "SFDP": "folder",
+	"SampleFilesWhere": "folder", "SFW": "folder",
+	"SampleFilesWhereDeep": "folder", "SFWD": "folder",
+	"Relevant": "folder", "R": "folder",
+	"RelevantMix": "folder", "RM": "folder",
+	"Semantic": "folder",
+}
+
+// Helpers returns the signatures of the helpers available in the templates by name,
+// e.g. to check calls without executing them
+func Helpers() map[string]reflect.Type {
+	funcs := helperFuncs(&Env{}, func(path string) string { return path }, func(ai.Image) string { return "" })
+
+	types := make(map[string]reflect.Type, len(funcs))
+	for name, f := range funcs {
+		types[name] = reflect.TypeOf(f)
 	}
+	return types
+}
 
-	return newMessages, nil
+// HelperPath returns "file" or "folder" if the first argument of the helper is a path
+// of that kind, else an empty string
+func HelperPath(name string) string {
+	return helperPaths[name]
 }
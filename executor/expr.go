@@ -0,0 +1,398 @@
+package executor
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed filter expression like "cr >= 5 && type == 'dragon'".
+//
+// Supported are field names (nested fields via "a.b"), string, number, boolean
+// and null literals, the comparison operators ==, !=, <, <=, >, >=, =~ (regex match),
+// "in" and "contains" (for lists and strings), the boolean operators &&, || and !
+// and parentheses.
+type Expr struct {
+	root node
+}
+
+type node interface {
+	eval(data any) any
+}
+
+type literalNode struct{ value any }
+
+type fieldNode struct{ path string }
+
+type notNode struct{ operand node }
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+// ParseExpr parses a filter expression
+func ParseExpr(expr string) (*Expr, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in expression %q", p.tokens[p.pos].text, expr)
+	}
+
+	return &Expr{root: root}, nil
+}
+
+// Match evaluates the expression against data and reports if the result is truthy
+func (e *Expr) Match(data any) bool {
+	return truthy(e.root.eval(data))
+}
+
+func (n literalNode) eval(any) any { return n.value }
+
+func (n fieldNode) eval(data any) any { return lookupPath(data, n.path) }
+
+func (n notNode) eval(data any) any { return !truthy(n.operand.eval(data)) }
+
+func (n binaryNode) eval(data any) any {
+	switch n.op {
+	case "&&":
+		return truthy(n.left.eval(data)) && truthy(n.right.eval(data))
+	case "||":
+		return truthy(n.left.eval(data)) || truthy(n.right.eval(data))
+	}
+
+	left, right := n.left.eval(data), n.right.eval(data)
+	switch n.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	case "<", "<=", ">", ">=":
+		cmp, ok := compare(left, right)
+		if !ok {
+			return false
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		default:
+			return cmp >= 0
+		}
+	case "=~":
+		if left == nil {
+			return false
+		}
+		matched, err := regexp.MatchString(fmt.Sprint(right), fmt.Sprint(left))
+		return err == nil && matched
+	case "in":
+		return contains(right, left)
+	case "contains":
+		return contains(left, right)
+	}
+
+	return false
+}
+
+// truthy reports if a value counts as true
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+
+	if f, ok := toNumber(value); ok {
+		return f != 0
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	}
+
+	return true
+}
+
+// toNumber converts numbers and numeric strings to float64
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func equal(left any, right any) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+
+	if cmp, ok := compare(left, right); ok {
+		return cmp == 0
+	}
+
+	return fmt.Sprint(left) == fmt.Sprint(right)
+}
+
+// compare compares numerically if both values are numeric and lexically if both
+// are strings.
+func compare(left any, right any) (int, bool) {
+	if left == nil || right == nil {
+		return 0, false
+	}
+
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if lok && rok {
+		switch {
+		case l < r:
+			return -1, true
+		case l > r:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	ls, lok := left.(string)
+	rs, rok := right.(string)
+	if lok && rok {
+		return strings.Compare(ls, rs), true
+	}
+
+	return 0, false
+}
+
+// contains checks if a list contains the element or a string the substring
+func contains(container any, element any) bool {
+	if s, ok := container.(string); ok {
+		return element != nil && strings.Contains(s, fmt.Sprint(element))
+	}
+
+	rv := reflect.ValueOf(container)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return false
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		if equal(rv.Index(i).Interface(), element) {
+			return true
+		}
+	}
+	return false
+}
+
+type token struct {
+	kind string // "op", "ident", "string", "number"
+	text string
+}
+
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!", "(", ")"}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c == '\'' || c == '"':
+			end := strings.IndexRune(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in expression %q", expr)
+			}
+			tokens = append(tokens, token{kind: "string", text: expr[i+1 : i+1+end]})
+			i += end + 2
+			continue
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(expr) && unicode.IsDigit(rune(expr[i+1]))):
+			start := i
+			i++
+			for i < len(expr) && (unicode.IsDigit(rune(expr[i])) || expr[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: "number", text: expr[start:i]})
+			continue
+		case unicode.IsLetter(c) || c == '_' || c == '$':
+			start := i
+			for i < len(expr) && (unicode.IsLetter(rune(expr[i])) || unicode.IsDigit(rune(expr[i])) || strings.ContainsRune("_.-$[]", rune(expr[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: "ident", text: expr[start:i]})
+			continue
+		}
+
+		matched := false
+		for _, op := range exprOperators {
+			if strings.HasPrefix(expr[i:], op) {
+				tokens = append(tokens, token{kind: "op", text: op})
+				i += len(op)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("unexpected character %q in expression %q", c, expr)
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// accept consumes the next token if it is one of the given operators or keywords
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	t, ok := p.peek()
+	if !ok || (t.kind != "op" && t.kind != "ident") {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "||", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseComparison() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">", "=~", "in", "contains")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: op, left: left, right: right}, nil
+}
+
+func (p *exprParser) parseUnary() (node, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+
+	switch t.kind {
+	case "string":
+		return literalNode{value: t.text}, nil
+	case "number":
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return literalNode{value: f}, nil
+	case "ident":
+		switch t.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null", "nil":
+			return literalNode{value: nil}, nil
+		}
+		return fieldNode{path: t.text}, nil
+	}
+
+	if t.text == "(" {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return inner, nil
+	}
+
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultExampleField is the frontmatter field of the input of an example
+const defaultExampleField = "input"
+
+// Example is an input and the expected output of a few-shot example
+type Example struct {
+	Name   string
+	Input  string
+	Output string
+}
+
+// Examples returns the examples of the folder sorted by name. An example is either
+// a pair of files named like "ghoul.in.md" and "ghoul.out.md", or a single file with
+// the input in a frontmatter field and the output as content. Other files are skipped.
+func (e *Env) Examples(folder string, field string) []Example {
+	if field == "" {
+		field = defaultExampleField
+	}
+
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		panic(err)
+	}
+
+	var examples []Example
+	for _, entry := range entries {
+		file := filepath.Join(folder, entry.Name())
+		if entry.IsDir() || e.Check(file) != nil || strings.Contains(entry.Name(), ".out.") {
+			continue
+		}
+
+		if name, ext, ok := strings.Cut(entry.Name(), ".in."); ok {
+			input, err := e.readFile(file)
+			if err != nil {
+				panic(err)
+			}
+			output, err := e.readFile(filepath.Join(folder, name+".out."+ext))
+			if err != nil {
+				panic(fmt.Errorf("example %s has no output: %w", entry.Name(), err))
+			}
+
+			examples = append(examples, Example{
+				Name:   name,
+				Input:  strings.TrimSpace(RemoveFrontmatter(entry.Name(), string(input))),
+				Output: strings.TrimSpace(RemoveFrontmatter(entry.Name(), string(output))),
+			})
+			continue
+		}
+
+		content, err := e.readFile(file)
+		if err != nil {
+			panic(err)
+		}
+		meta, body, err := ParseFrontmatter(entry.Name(), string(content))
+		if err != nil {
+			panic(err)
+		}
+		value, ok := meta[field]
+		if !ok {
+			continue
+		}
+
+		examples = append(examples, Example{
+			Name:   strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())),
+			Input:  strings.TrimSpace(exampleInput(value)),
+			Output: strings.TrimSpace(body),
+		})
+	}
+
+	sort.Slice(examples, func(i, j int) bool {
+		return examples[i].Name < examples[j].Name
+	})
+	return examples
+}
+
+// SampleExamples returns count random examples of the folder
+func (e *Env) SampleExamples(folder string, count int, field string) []Example {
+	examples := e.Examples(folder, field)
+	if count > len(examples) {
+		count = len(examples)
+	}
+
+	e.rng.Shuffle(len(examples), func(i, j int) {
+		examples[i], examples[j] = examples[j], examples[i]
+	})
+
+	return examples[:count]
+}
+
+// FewShot samples count examples of the folder and returns them as alternating user
+// and assistant messages, which are inserted at the position of the call. Content
+// after the call continues the message the call is in.
+func (e *Env) FewShot(folder string, count int, field string) string {
+	var result strings.Builder
+	for _, example := range e.SampleExamples(folder, count, field) {
+		fmt.Fprintf(&result, "\n"+roleSeparator+"\n%s\n", e.nonce, "user", example.Input)
+		fmt.Fprintf(&result, "\n"+roleSeparator+"\n%s\n", e.nonce, "assistant", example.Output)
+	}
+	if result.Len() > 0 {
+		fmt.Fprintf(&result, "\n"+resumeSeparator+"\n", e.nonce)
+	}
+	return result.String()
+}
+
+// exampleInput converts the value of the frontmatter field to the input. Structured
+// values are written as YAML.
+func exampleInput(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
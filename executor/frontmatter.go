@@ -1,8 +1,11 @@
 package executor
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // RemoveFrontmatter removes YAML frontmatter from markdown content.
@@ -29,3 +32,50 @@ func RemoveFrontmatter(file string, content string) string {
 	// If we didn't find a closing delimiter, return original content
 	return content
 }
+
+// ParseFrontmatter splits markdown content into its parsed YAML frontmatter and the
+// remaining content. Files without frontmatter return an empty map.
+func ParseFrontmatter(file string, content string) (map[string]any, string, error) {
+	meta := map[string]any{}
+
+	body := RemoveFrontmatter(file, content)
+	if body == content {
+		return meta, content, nil
+	}
+
+	// The frontmatter is everything between the first line and the closing delimiter
+	raw := strings.TrimSuffix(content, body)
+	raw = raw[strings.Index(raw, "\n")+1:]
+	raw = raw[:strings.LastIndex(strings.TrimSuffix(raw, "\n"), "\n")+1]
+
+	if err := yaml.Unmarshal([]byte(raw), &meta); err != nil {
+		return map[string]any{}, body, fmt.Errorf("error parsing frontmatter of %s: %w", file, err)
+	}
+	if meta == nil {
+		meta = map[string]any{}
+	}
+
+	return meta, body, nil
+}
+
+// FormatFrontmatterFields renders the given frontmatter fields as "key: value" lines.
+// The field "*" renders the complete frontmatter.
+func FormatFrontmatterFields(meta map[string]any, fields []string) string {
+	var result strings.Builder
+	for _, field := range fields {
+		if field == "*" {
+			out, err := yaml.Marshal(meta)
+			if err == nil && len(meta) > 0 {
+				result.Write(out)
+			}
+			continue
+		}
+
+		value := lookupPath(meta, field)
+		if value == nil {
+			continue
+		}
+		result.WriteString(fmt.Sprintf("%s: %s\n", field, formatCell(value)))
+	}
+	return result.String()
+}
@@ -1,6 +1,13 @@
 package executor
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
 
 func TestRemoveFrontmatter(t *testing.T) {
 	tests := []struct {
@@ -56,3 +63,46 @@ func TestRemoveFrontmatter(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFrontmatter(t *testing.T) {
+	meta, body, err := ParseFrontmatter("test.md", "---\ncr: 5\ntype: dragon\ntags:\n  - fire\n---\n# Dragon")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"cr": 5, "type": "dragon", "tags": []any{"fire"}}, meta)
+	assert.Equal(t, "# Dragon", body)
+
+	meta, body, err = ParseFrontmatter("test.md", "# No frontmatter")
+	require.NoError(t, err)
+	assert.Empty(t, meta)
+	assert.Equal(t, "# No frontmatter", body)
+
+	meta, _, err = ParseFrontmatter("test.md", "---\n---\nEmpty")
+	require.NoError(t, err)
+	assert.Empty(t, meta)
+
+	_, _, err = ParseFrontmatter("test.md", "---\ncr: [5\n---\nBroken")
+	assert.Error(t, err)
+}
+
+func TestSampleFilesWhere(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"red.md":        "---\ncr: 17\ntype: dragon\n---\nRed Dragon",
+		"wyrmling.md":   "---\ncr: 2\ntype: dragon\n---\nWyrmling",
+		"lich.md":       "---\ncr: 21\ntype: undead\n---\nLich",
+		"plain.md":      "No frontmatter",
+		"sub/silver.md": "---\ncr: 16\ntype: dragon\n---\nSilver Dragon",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	env, err := NewEnv(root, nil, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "====== File: red.md\ncr: 17\nRed Dragon\n\n", env.SampleFilesWhere(root, "cr >= 5 && type == 'dragon'", 3, true, "cr"))
+	assert.Equal(t, "Red Dragon\n\n", env.SampleFilesWhere(root, "cr >= 5 && type == 'dragon'", 3, false))
+	assert.Len(t, env.SampleFilesWhereDeep(root, "type == 'dragon'", 10, false), len("Red Dragon\n\nWyrmling\n\nSilver Dragon\n\n"))
+	assert.Equal(t, map[string]any{"cr": 21, "type": "undead"}, env.Frontmatter(filepath.Join(root, "lich.md")))
+}
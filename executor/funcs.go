@@ -2,8 +2,6 @@ package executor
 
 import (
 	"fmt"
-	"io/ioutil"
-	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,17 +11,25 @@ import (
 
 // SampleFiles reads count random files from the folder and appends them as a string.
 // If meta is set the file name is included
-func SampleFiles(folder string, count int, meta bool) string {
-	files, err := os.ReadDir(folder)
+func (e *Env) SampleFiles(folder string, count int, meta bool) string {
+	entries, err := os.ReadDir(folder)
 	if err != nil {
 		panic(err)
 	}
 
+	// Skip entries (e.g. symlinks) that point outside the allowed paths
+	var files []os.DirEntry
+	for _, entry := range entries {
+		if e.Check(filepath.Join(folder, entry.Name())) == nil {
+			files = append(files, entry)
+		}
+	}
+
 	if count > len(files) {
 		count = len(files)
 	}
 
-	rand.Shuffle(len(files), func(i, j int) {
+	e.rng.Shuffle(len(files), func(i, j int) {
 		files[i], files[j] = files[j], files[i]
 	})
 
@@ -34,7 +40,7 @@ func SampleFiles(folder string, count int, meta bool) string {
 			continue
 		}
 
-		content, err := os.ReadFile(filepath.Join(folder, file.Name()))
+		content, err := e.readFile(filepath.Join(folder, file.Name()))
 		if err != nil {
 			result.WriteString(fmt.Sprintf("Error reading file %s: %v\n", file.Name(), err))
 			continue
@@ -52,13 +58,13 @@ func SampleFiles(folder string, count int, meta bool) string {
 
 // SampleFilesDeep reads count random files from the folder and appends them as a string.
 // If meta is set the file name is included. This is a recursive function.
-func SampleFilesDeep(folder string, count int, meta bool) string {
+func (e *Env) SampleFilesDeep(folder string, count int, meta bool) string {
 	var possibleFiles []string
 	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() {
+		if !info.IsDir() && e.Check(path) == nil {
 			possibleFiles = append(possibleFiles, path)
 		}
 		return nil
@@ -67,14 +73,18 @@ func SampleFilesDeep(folder string, count int, meta bool) string {
 		panic(err)
 	}
 
-	rand.Shuffle(len(possibleFiles), func(i, j int) {
+	if count > len(possibleFiles) {
+		count = len(possibleFiles)
+	}
+
+	e.rng.Shuffle(len(possibleFiles), func(i, j int) {
 		possibleFiles[i], possibleFiles[j] = possibleFiles[j], possibleFiles[i]
 	})
 
 	var result strings.Builder
 	for i := 0; i < count; i++ {
 		file := possibleFiles[i]
-		content, err := os.ReadFile(file)
+		content, err := e.readFile(file)
 		if err != nil {
 			result.WriteString(fmt.Sprintf("Error reading file %s: %v\n", file, err))
 			continue
@@ -91,8 +101,8 @@ func SampleFilesDeep(folder string, count int, meta bool) string {
 }
 
 // SampleLines reads count random lines from the file and appends them as a string
-func SampleLines(file string, count int) string {
-	content, err := ioutil.ReadFile(file)
+func (e *Env) SampleLines(file string, count int) string {
+	content, err := e.readFile(file)
 	if err != nil {
 		panic(err)
 	}
@@ -102,7 +112,7 @@ func SampleLines(file string, count int) string {
 		count = len(lines)
 	}
 
-	rand.Shuffle(len(lines), func(i, j int) {
+	e.rng.Shuffle(len(lines), func(i, j int) {
 		lines[i], lines[j] = lines[j], lines[i]
 	})
 
@@ -116,8 +126,8 @@ func SampleLines(file string, count int) string {
 }
 
 // File reads a file and returns its content
-func File(file string) string {
-	content, err := ioutil.ReadFile(file)
+func (e *Env) File(file string) string {
+	content, err := e.readFile(file)
 	if err != nil {
 		panic(err)
 	}
@@ -125,8 +135,8 @@ func File(file string) string {
 }
 
 // SampleChunk reads a file and returns a random chunk with lines count
-func SampleChunk(file string, count int) string {
-	content, err := ioutil.ReadFile(file)
+func (e *Env) SampleChunk(file string, count int) string {
+	content, err := e.readFile(file)
 	if err != nil {
 		panic(err)
 	}
@@ -136,12 +146,13 @@ func SampleChunk(file string, count int) string {
 		count = len(lines)
 	}
 
-	start := rand.Intn(len(lines) - count)
+	start := e.rng.Intn(len(lines) - count + 1)
 	end := start + count
 	return strings.Join(lines[start:end], "\n")
 }
 
-// RunCommand runs a command and returns its output
+// RunCommand runs a command and returns its output. The command is not confined to
+// the allowed paths, it can read and write anything the user can.
 func RunCommand(command string, args ...string) string {
 	cmd := exec.Command(command, args...)
 	out, err := cmd.Output()
@@ -153,7 +164,7 @@ func RunCommand(command string, args ...string) string {
 
 // SampleFilesPattern reads count random files from the folder whose content matches the pattern and appends them as a string.
 // If meta is set the file name is included
-func SampleFilesPattern(folder string, pattern string, count int, meta bool) string {
+func (e *Env) SampleFilesPattern(folder string, pattern string, count int, meta bool) string {
 	files, err := os.ReadDir(folder)
 	if err != nil {
 		panic(err)
@@ -165,7 +176,7 @@ func SampleFilesPattern(folder string, pattern string, count int, meta bool) str
 			continue
 		}
 
-		content, err := os.ReadFile(filepath.Join(folder, file.Name()))
+		content, err := e.readFile(filepath.Join(folder, file.Name()))
 		if err != nil {
 			continue
 		}
@@ -184,14 +195,14 @@ func SampleFilesPattern(folder string, pattern string, count int, meta bool) str
 		count = len(matchingFiles)
 	}
 
-	rand.Shuffle(len(matchingFiles), func(i, j int) {
+	e.rng.Shuffle(len(matchingFiles), func(i, j int) {
 		matchingFiles[i], matchingFiles[j] = matchingFiles[j], matchingFiles[i]
 	})
 
 	var result strings.Builder
 	for i := 0; i < count; i++ {
 		file := matchingFiles[i]
-		content, err := os.ReadFile(filepath.Join(folder, file.Name()))
+		content, err := e.readFile(filepath.Join(folder, file.Name()))
 		if err != nil {
 			result.WriteString(fmt.Sprintf("Error reading file %s: %v\n", file.Name(), err))
 			continue
@@ -209,14 +220,14 @@ func SampleFilesPattern(folder string, pattern string, count int, meta bool) str
 
 // SampleFilesPatternDeep reads count random files from the folder and its subdirectories whose content matches the pattern
 // and appends them as a string. If meta is set the file name is included.
-func SampleFilesPatternDeep(folder string, pattern string, count int, meta bool) string {
+func (e *Env) SampleFilesPatternDeep(folder string, pattern string, count int, meta bool) string {
 	var matchingFiles []string
 	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if !info.IsDir() {
-			content, err := os.ReadFile(path)
+			content, err := e.readFile(path)
 			if err != nil {
 				return nil
 			}
@@ -240,14 +251,14 @@ func SampleFilesPatternDeep(folder string, pattern string, count int, meta bool)
 		count = len(matchingFiles)
 	}
 
-	rand.Shuffle(len(matchingFiles), func(i, j int) {
+	e.rng.Shuffle(len(matchingFiles), func(i, j int) {
 		matchingFiles[i], matchingFiles[j] = matchingFiles[j], matchingFiles[i]
 	})
 
 	var result strings.Builder
 	for i := 0; i < count; i++ {
 		file := matchingFiles[i]
-		content, err := os.ReadFile(file)
+		content, err := e.readFile(file)
 		if err != nil {
 			result.WriteString(fmt.Sprintf("Error reading file %s: %v\n", file, err))
 			continue
@@ -262,3 +273,111 @@ func SampleFilesPatternDeep(folder string, pattern string, count int, meta bool)
 
 	return result.String()
 }
+
+// Frontmatter reads a markdown file and returns its parsed frontmatter
+func (e *Env) Frontmatter(file string) map[string]any {
+	content, err := e.readFile(file)
+	if err != nil {
+		panic(err)
+	}
+
+	meta, _, err := ParseFrontmatter(file, string(content))
+	if err != nil {
+		panic(err)
+	}
+	return meta
+}
+
+// SampleFilesWhere reads count random files from the folder whose frontmatter matches the
+// filter expression and appends them as a string. If meta is set the file name and the
+// given frontmatter fields are included.
+func (e *Env) SampleFilesWhere(folder string, expr string, count int, meta bool, fields ...string) string {
+	files, err := os.ReadDir(folder)
+	if err != nil {
+		panic(err)
+	}
+
+	var paths []string
+	for _, file := range files {
+		if !file.IsDir() {
+			paths = append(paths, filepath.Join(folder, file.Name()))
+		}
+	}
+
+	return e.sampleFilesWhere(folder, paths, expr, count, meta, fields)
+}
+
+// SampleFilesWhereDeep reads count random files from the folder and its subdirectories whose
+// frontmatter matches the filter expression and appends them as a string. If meta is set the
+// file name and the given frontmatter fields are included.
+func (e *Env) SampleFilesWhereDeep(folder string, expr string, count int, meta bool, fields ...string) string {
+	var paths []string
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return e.sampleFilesWhere(folder, paths, expr, count, meta, fields)
+}
+
+func (e *Env) sampleFilesWhere(folder string, paths []string, expr string, count int, meta bool, fields []string) string {
+	filter, err := ParseExpr(expr)
+	if err != nil {
+		panic(err)
+	}
+
+	type matchingFile struct {
+		name string
+		meta map[string]any
+		body string
+	}
+
+	var matchingFiles []matchingFile
+	for _, path := range paths {
+		content, err := e.readFile(path)
+		if err != nil {
+			continue
+		}
+
+		// Files with broken frontmatter are treated as having none
+		frontmatter, body, _ := ParseFrontmatter(path, string(content))
+		if !filter.Match(frontmatter) {
+			continue
+		}
+
+		name, err := filepath.Rel(folder, path)
+		if err != nil {
+			name = path
+		}
+		matchingFiles = append(matchingFiles, matchingFile{name: name, meta: frontmatter, body: body})
+	}
+
+	if count > len(matchingFiles) {
+		count = len(matchingFiles)
+	}
+
+	e.rng.Shuffle(len(matchingFiles), func(i, j int) {
+		matchingFiles[i], matchingFiles[j] = matchingFiles[j], matchingFiles[i]
+	})
+
+	var result strings.Builder
+	for i := 0; i < count; i++ {
+		file := matchingFiles[i]
+		if meta {
+			result.WriteString(fmt.Sprintf("====== File: %s\n", file.name))
+			result.WriteString(FormatFrontmatterFields(file.meta, fields))
+		}
+		result.WriteString(file.body)
+		result.WriteString("\n\n")
+	}
+
+	return result.String()
+}
@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleFilesDeep(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("first"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "b.txt"), []byte("second"), 0644))
+
+	env, err := NewEnv(root, nil, false)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		count int
+		files int
+	}{
+		{name: "less than available", count: 1, files: 1},
+		{name: "all files", count: 2, files: 2},
+		{name: "more than available", count: 5, files: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := env.SampleFilesDeep(root, tt.count, true)
+			assert.Equal(t, tt.files, strings.Count(got, "====== File: "), got)
+		})
+	}
+}
+
+func TestSampleChunk(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "lines.txt")
+	require.NoError(t, os.WriteFile(file, []byte("1\n2\n3"), 0644))
+
+	env, err := NewEnv(root, nil, false)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		count  int
+		chunks []string
+	}{
+		{name: "single lines", count: 1, chunks: []string{"1", "2", "3"}},
+		{name: "two lines", count: 2, chunks: []string{"1\n2", "2\n3"}},
+		{name: "all lines", count: 3, chunks: []string{"1\n2\n3"}},
+		{name: "more than available", count: 5, chunks: []string{"1\n2\n3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := map[string]bool{}
+			for i := 0; i < 100; i++ {
+				chunk := env.SampleChunk(file, tt.count)
+				assert.Contains(t, tt.chunks, chunk)
+				seen[chunk] = true
+			}
+			assert.Len(t, seen, len(tt.chunks))
+		})
+	}
+}
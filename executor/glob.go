@@ -0,0 +1,177 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/denormal/go-gitignore"
+)
+
+// binarySniffLen is the amount of bytes that are checked to detect binary files
+const binarySniffLen = 8000
+
+// globEscaper escapes the glob meta characters of a path, so it can be used as the
+// base of a pattern
+var globEscaper = strings.NewReplacer("*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`, "{", `\{`, "}", `\}`)
+
+// Glob returns the paths of all files matching the doublestar glob pattern. Paths are
+// relative to the root if the pattern is relative. Files matching one of the exclude
+// patterns or ignored by a .gitignore / .claiignore are skipped, as are hidden and
+// binary files.
+func (e *Env) Glob(pattern string, exclude ...string) []string {
+	files, err := e.glob(pattern, exclude)
+	if err != nil {
+		panic(err)
+	}
+	return files
+}
+
+// Files reads all files matching the glob pattern and appends them as a string.
+// If meta is set the file name is included
+func (e *Env) Files(pattern string, meta bool, exclude ...string) string {
+	return e.writeFiles(e.Glob(pattern, exclude...), meta)
+}
+
+// SampleGlob reads count random files matching the glob pattern and appends them as a string.
+// If meta is set the file name is included
+func (e *Env) SampleGlob(pattern string, count int, meta bool, exclude ...string) string {
+	files := e.Glob(pattern, exclude...)
+	if count > len(files) {
+		count = len(files)
+	}
+
+	e.rng.Shuffle(len(files), func(i, j int) {
+		files[i], files[j] = files[j], files[i]
+	})
+
+	return e.writeFiles(files[:count], meta)
+}
+
+// writeFiles reads the files and appends them as a string
+func (e *Env) writeFiles(files []string, meta bool) string {
+	var result strings.Builder
+	for _, file := range files {
+		content, err := e.readFile(e.abs(file))
+		if err != nil {
+			result.WriteString(fmt.Sprintf("Error reading file %s: %v\n", file, err))
+			continue
+		}
+
+		if meta {
+			result.WriteString(fmt.Sprintf("====== File: %s\n", file))
+		}
+		result.WriteString(RemoveFrontmatter(file, string(content)))
+		result.WriteString("\n\n")
+	}
+
+	return result.String()
+}
+
+// abs returns the absolute path of a path relative to the root
+func (e *Env) abs(file string) string {
+	file = filepath.FromSlash(file)
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(e.Root, file)
+}
+
+func (e *Env) glob(pattern string, exclude []string) ([]string, error) {
+	pattern = filepath.ToSlash(pattern)
+	if !doublestar.ValidatePattern(pattern) {
+		return nil, fmt.Errorf("invalid glob pattern %q", pattern)
+	}
+	relative := !filepath.IsAbs(filepath.FromSlash(pattern))
+
+	// Only the part of the pattern after the base is matched, so glob meta
+	// characters in the names of the root or the base don't matter
+	dir, rest := doublestar.SplitPattern(pattern)
+	base, err := e.Resolve(filepath.FromSlash(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := parseIgnore(e.Root, strings.Join(exclude, "\n"))
+
+	// Collect the ignore files of all directories between the root and the base
+	// of the walk, as they apply to the walked files too.
+	var ignores []gitignore.GitIgnore
+	if isWithin(e.Root, base) {
+		dir := e.Root
+		rel, _ := filepath.Rel(e.Root, base)
+		for _, part := range append([]string{""}, strings.Split(rel, string(filepath.Separator))...) {
+			dir = filepath.Join(dir, part)
+			if dir == base {
+				break
+			}
+			ignores = append(ignores, loadIgnoreFiles(dir)...)
+		}
+	}
+
+	var files []string
+	walkIgnores := map[string][]gitignore.GitIgnore{}
+	err = filepath.WalkDir(base, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		dirIgnores := ignores
+		if file != base {
+			dirIgnores = walkIgnores[filepath.Dir(file)]
+			if strings.HasPrefix(d.Name(), ".") || isIgnored([]gitignore.GitIgnore{excluded}, file, d.IsDir()) || isIgnored(dirIgnores, file, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if d.IsDir() {
+			walkIgnores[file] = append(dirIgnores[:len(dirIgnores):len(dirIgnores)], loadIgnoreFiles(file)...)
+			return nil
+		}
+
+		rel, err := filepath.Rel(base, file)
+		if err != nil {
+			return err
+		}
+		if !doublestar.MatchUnvalidated(rest, filepath.ToSlash(rel)) || e.Check(file) != nil || isBinaryFile(file) {
+			return nil
+		}
+
+		if relative {
+			file, _ = filepath.Rel(e.Root, file)
+		}
+		files = append(files, filepath.ToSlash(file))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// isBinaryFile checks if the beginning of the file contains a NUL byte, the same
+// heuristic git uses.
+func isBinaryFile(file string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false
+	}
+
+	return bytes.IndexByte(buf[:n], 0) >= 0
+}
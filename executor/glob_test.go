@@ -0,0 +1,143 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/denormal/go-gitignore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobPatterns(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.md", "a.txt", "a.csv", "file1.md", "c.md", "dir/sub/a.md", "monsters/dragons/red.md", "monsters/a/b/c.txt", "items/sword.md", "npcs/bob.md"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0644))
+	}
+
+	env, err := NewEnv(root, nil, false)
+	require.NoError(t, err)
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "*.md", want: []string{"a.md", "c.md", "file1.md"}},
+		{pattern: "**/a.md", want: []string{"a.md", "dir/sub/a.md"}},
+		{pattern: "monsters/**/*.md", want: []string{"monsters/dragons/red.md"}},
+		{pattern: "monsters/**", want: []string{"monsters/a/b/c.txt", "monsters/dragons/red.md"}},
+		{pattern: "*.{md,txt}", want: []string{"a.md", "a.txt", "c.md", "file1.md"}},
+		{pattern: "{monsters,npcs}/*.md", want: []string{"npcs/bob.md"}},
+		{pattern: "file?.md", want: []string{"file1.md"}},
+		{pattern: "[ab].md", want: []string{"a.md"}},
+		{pattern: "items/sword.md", want: []string{"items/sword.md"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.want, env.Glob(tt.pattern))
+		})
+	}
+
+	assert.Panics(t, func() { env.Glob("[a.md") })
+}
+
+func TestGlobMetaCharacters(t *testing.T) {
+	// Meta characters in the root and in the base of the pattern are matched literally
+	root := filepath.Join(t.TempDir(), "vault [old] {a,b}")
+	for _, name := range []string{"notes [draft]/a.md", "notes [draft]/b.txt", "notes d/c.md"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0644))
+	}
+
+	env, err := NewEnv(root, nil, false)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"notes [draft]/a.md", "notes d/c.md"}, env.Glob("**/*.md"))
+	assert.Equal(t, []string{"notes [draft]/a.md"}, env.Glob(`notes \[draft\]/*.md`))
+	assert.Equal(t, []string{"notes d/c.md"}, env.Glob("notes [d]/*.md"))
+
+	abs := filepath.ToSlash(filepath.Join(root, "notes [draft]"))
+	escaped := strings.NewReplacer("[", `\[`, "]", `\]`, "{", `\{`, "}", `\}`).Replace(abs)
+	assert.Equal(t, []string{abs + "/a.md"}, env.Glob(escaped+"/*.md"))
+}
+
+func TestIgnoreRules(t *testing.T) {
+	ignores := []gitignore.GitIgnore{
+		parseIgnore("/vault", "# comment\n*.log\nbuild/\n/secret.md\n!keep.log\ndocs/**/draft.md\n"),
+		parseIgnore("/vault/sub", "!again.log\n"),
+	}
+
+	tests := []struct {
+		file  string
+		isDir bool
+		want  bool
+	}{
+		{file: "/vault/a.log", want: true},
+		{file: "/vault/sub/a.log", want: true},
+		{file: "/vault/sub/keep.log", want: false},
+		{file: "/vault/sub/again.log", want: false},
+		{file: "/vault/again.log", want: true},
+		{file: "/vault/build", isDir: true, want: true},
+		{file: "/vault/build", isDir: false, want: false},
+		{file: "/vault/secret.md", want: true},
+		{file: "/vault/sub/secret.md", want: false},
+		{file: "/vault/docs/a/b/draft.md", want: true},
+		{file: "/other/a.log", want: false},
+		{file: "/vault2/a.log", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			assert.Equal(t, tt.want, isIgnored(ignores, filepath.FromSlash(tt.file), tt.isDir))
+		})
+	}
+}
+
+func TestGlob(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]string{
+		".gitignore":                  "ignored/\n",
+		"monsters/.claiignore":        "draft*.md\n",
+		"monsters/dragon.md":          "---\ncr: 5\n---\nDragon",
+		"monsters/undead/ghoul.md":    "Ghoul",
+		"monsters/draft-lich.md":      "Lich",
+		"monsters/token.md":           "PNG\x00\x01",
+		"monsters/notes.txt":          "Notes",
+		"monsters/ignored/secret.md":  "Secret",
+		".obsidian/workspace.md":      "Workspace",
+		"monsters/.hidden/hidden.md":  "Hidden",
+		"monsters/undead/zombie.md":   "Zombie",
+		"monsters/undead/skeleton.md": "Skeleton",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	env, err := NewEnv(root, nil, false)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		"monsters/dragon.md",
+		"monsters/undead/ghoul.md",
+		"monsters/undead/zombie.md",
+		"monsters/undead/skeleton.md",
+	}, env.Glob("monsters/**/*.md"))
+
+	assert.ElementsMatch(t, []string{
+		"monsters/dragon.md",
+		"monsters/undead/ghoul.md",
+	}, env.Glob("**/*.md", "zombie.md", "monsters/undead/skel*"))
+
+	assert.Equal(t, "====== File: monsters/dragon.md\nDragon\n\n", env.Files("monsters/*.md", true))
+	assert.Len(t, env.Glob("monsters/*.{md,txt}"), 2)
+	assert.Panics(t, func() { env.Glob("../**/*.md") })
+}
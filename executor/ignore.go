@@ -0,0 +1,53 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/denormal/go-gitignore"
+)
+
+// ignoreFiles are the files in a directory whose patterns exclude files from the glob helpers
+var ignoreFiles = []string{".gitignore", ".claiignore"}
+
+// loadIgnoreFiles reads the ignore files in dir
+func loadIgnoreFiles(dir string) []gitignore.GitIgnore {
+	var ignores []gitignore.GitIgnore
+	for _, name := range ignoreFiles {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		ignores = append(ignores, parseIgnore(dir, string(content)))
+	}
+	return ignores
+}
+
+// parseIgnore parses the content of a .gitignore style file located in base. Invalid
+// patterns are skipped.
+func parseIgnore(base string, content string) gitignore.GitIgnore {
+	return gitignore.New(strings.NewReader(content), base, func(gitignore.Error) bool {
+		return true
+	})
+}
+
+// isIgnored checks if the file is ignored. The ignore files are ordered from the
+// outermost to the innermost directory. Like in git the last matching pattern wins,
+// so the patterns of deeper directories take precedence.
+func isIgnored(ignores []gitignore.GitIgnore, file string, isDir bool) bool {
+	for i := len(ignores) - 1; i >= 0; i-- {
+		if !isWithin(ignores[i].Base(), file) {
+			continue
+		}
+
+		rel, err := filepath.Rel(ignores[i].Base(), file)
+		if err != nil || rel == "." {
+			continue
+		}
+		if match := ignores[i].Relative(filepath.ToSlash(rel), isDir); match != nil {
+			return match.Ignore()
+		}
+	}
+	return false
+}
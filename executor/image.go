@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"github.com/bigjk/clai/ai"
+)
+
+// defaultImageSize is the default maximum width and height of attached images
+const defaultImageSize = 1024
+
+// passthroughImageTypes are formats that can't be decoded and are attached unchanged
+var passthroughImageTypes = map[string]string{
+	".webp": "image/webp",
+}
+
+// Image reads an image and scales it down to fit into maxSize x maxSize pixels.
+// PNG and GIF images are encoded as PNG, everything else as JPEG.
+func (e *Env) Image(file string, maxSize int) ai.Image {
+	if maxSize <= 0 {
+		maxSize = defaultImageSize
+	}
+
+	content, err := e.readFile(file)
+	if err != nil {
+		panic(err)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		if mediaType, ok := passthroughImageTypes[strings.ToLower(filepath.Ext(file))]; ok {
+			return ai.Image{Name: filepath.Base(file), MediaType: mediaType, Data: content}
+		}
+		panic(fmt.Errorf("error decoding image %s: %w", file, err))
+	}
+
+	bounds := img.Bounds()
+	result := ai.Image{
+		Name:      filepath.Base(file),
+		MediaType: "image/" + format,
+		Data:      content,
+		Width:     bounds.Dx(),
+		Height:    bounds.Dy(),
+	}
+
+	if result.Width <= maxSize && result.Height <= maxSize {
+		return result
+	}
+
+	scaled := scaleImage(img, maxSize)
+	buf := &bytes.Buffer{}
+	if format == "png" || format == "gif" {
+		err = png.Encode(buf, scaled)
+		result.MediaType = "image/png"
+	} else {
+		err = jpeg.Encode(buf, scaled, &jpeg.Options{Quality: 85})
+		result.MediaType = "image/jpeg"
+	}
+	if err != nil {
+		panic(fmt.Errorf("error encoding image %s: %w", file, err))
+	}
+
+	result.Data = buf.Bytes()
+	result.Width = scaled.Bounds().Dx()
+	result.Height = scaled.Bounds().Dy()
+	return result
+}
+
+// scaleImage scales the image down to fit into maxSize x maxSize pixels by averaging
+// the source pixels covered by each target pixel. Smaller images are only copied.
+func scaleImage(img image.Image, maxSize int) *image.NRGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	scale := 1.0
+	if width > maxSize || height > maxSize {
+		scale = float64(maxSize) / float64(max(width, height))
+	}
+	targetWidth := max(1, int(float64(width)*scale+0.5))
+	targetHeight := max(1, int(float64(height)*scale+0.5))
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, targetWidth, targetHeight))
+	for y := 0; y < targetHeight; y++ {
+		y0 := bounds.Min.Y + y*height/targetHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/targetHeight)
+		for x := 0; x < targetWidth; x++ {
+			x0 := bounds.Min.X + x*width/targetWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/targetWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(img.At(sx, sy)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+
+			scaled.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return scaled
+}
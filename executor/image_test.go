@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestImage(t *testing.T, file string, width int, height int) {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	f, err := os.Create(file)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, png.Encode(f, img))
+}
+
+func TestImage(t *testing.T) {
+	root := t.TempDir()
+	writeTestImage(t, filepath.Join(root, "map.png"), 300, 150)
+
+	env, err := NewEnv(root, nil, false)
+	require.NoError(t, err)
+
+	img := env.Image(filepath.Join(root, "map.png"), 100)
+	assert.Equal(t, "image/png", img.MediaType)
+	assert.Equal(t, 100, img.Width)
+	assert.Equal(t, 50, img.Height)
+
+	decoded, err := png.Decode(bytes.NewReader(img.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), decoded.Bounds())
+
+	original := env.Image(filepath.Join(root, "map.png"), 0)
+	assert.Equal(t, 300, original.Width)
+	assert.Panics(t, func() { env.Image(filepath.Join(root, "missing.png"), 0) })
+}
+
+func TestExecuteAttachesImages(t *testing.T) {
+	root := t.TempDir()
+	writeTestImage(t, filepath.Join(root, "map.png"), 10, 10)
+
+	messages := []ai.Message{
+		{Role: "system", Content: "You are a cartographer."},
+		{Role: "user", Content: "Describe {{ call .Image \"map.png\" }}this map."},
+	}
+
+	res, err := Execute(messages, "", root)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Empty(t, res[0].Images)
+	assert.Equal(t, "Describe this map.", res[1].Content)
+	require.Len(t, res[1].Images, 1)
+	assert.Equal(t, "map.png", res[1].Images[0].Name)
+}
+
+func TestExecuteAttachments(t *testing.T) {
+	root := t.TempDir()
+	writeTestImage(t, filepath.Join(root, "map.png"), 10, 10)
+
+	messages := []ai.Message{
+		{Role: "user", Content: "Describe this map.", Name: "dm", Attachments: []string{"map.png"}},
+	}
+
+	res, err := Execute(messages, "", root)
+	require.NoError(t, err)
+	require.Len(t, res[0].Images, 1)
+	assert.Equal(t, "map.png", res[0].Images[0].Name)
+	assert.Equal(t, "dm", res[0].Name)
+	assert.Empty(t, res[0].Attachments)
+
+	messages[0].Attachments = []string{"../outside.png"}
+	_, err = Execute(messages, "", root)
+	var msgErr *MessageError
+	require.ErrorAs(t, err, &msgErr)
+	assert.Equal(t, 0, msgErr.Index)
+}
@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bigjk/clai/ai"
+)
+
+// The separators and placeholders contain the nonce of the execution, so that
+// content of the input, of files or of command output can't be read as one. The
+// NUL characters keep them apart from the text around them.
+
+// messageSeparator starts a message in the joined template
+const messageSeparator = "\x00clai:%s:message:%d\x00"
+
+// roleSeparator starts a message with the role that isn't part of the template, e.g.
+// the examples of FewShot
+const roleSeparator = "\x00clai:%s:role:%s\x00"
+
+// resumeSeparator continues the last message of the template after inserted messages
+const resumeSeparator = "\x00clai:%s:resume\x00"
+
+// imagePlaceholder marks the position of an attached image in the rendered content
+const imagePlaceholder = "\x00clai:%s:image:%d\x00"
+
+var (
+	templateLocation = regexp.MustCompile(`^template: [^:]*:\d+:(\d+:)? ?`)
+	templateLine     = regexp.MustCompile(`^template: [^:]*:(\d+):`)
+)
+
+// newNonce returns a random nonce for the separators of an execution
+func newNonce() string {
+	nonce := make([]byte, 8)
+	_, _ = rand.Read(nonce)
+	return hex.EncodeToString(nonce)
+}
+
+// separatorPattern matches the separators of the nonce
+func separatorPattern(nonce string) *regexp.Regexp {
+	return regexp.MustCompile("\x00clai:" + regexp.QuoteMeta(nonce) + ":(message|role|resume):?([^\x00]*)\x00")
+}
+
+// imagePlaceholderPattern matches the image placeholders of the nonce
+func imagePlaceholderPattern(nonce string) *regexp.Regexp {
+	return regexp.MustCompile("\x00clai:" + regexp.QuoteMeta(nonce) + ":image:(\\d+)\x00")
+}
+
+// joinMessages joins the messages to a single template. It returns the line each
+// content starts on in the template.
+func joinMessages(messages []ai.Message, nonce string) (string, []int) {
+	var b strings.Builder
+	starts := make([]int, len(messages))
+	line := 1
+	for i, msg := range messages {
+		fmt.Fprintf(&b, messageSeparator+"\n", nonce, i)
+		line++
+		starts[i] = line
+
+		b.WriteString(msg.Content)
+		b.WriteString("\n")
+		line += strings.Count(msg.Content, "\n") + 1
+	}
+	return b.String(), starts
+}
+
+// renderedMessage is the rendered content of a message
+type renderedMessage struct {
+	// index is the message of the template, -1 for inserted messages
+	index int
+	// role is the role of inserted messages
+	role string
+	// resumed is set if the content continues the message after inserted messages
+	resumed bool
+	content string
+}
+
+// splitMessages splits the rendered template at the separators of the nonce. Content
+// before the first separator is dropped. count is the number of messages of the
+// template.
+func splitMessages(rendered string, nonce string, count int) ([]renderedMessage, error) {
+	var parts []renderedMessage
+	last := -1
+	matches := separatorPattern(nonce).FindAllStringSubmatchIndex(rendered, -1)
+	for i, match := range matches {
+		end := len(rendered)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		part := renderedMessage{index: -1, content: rendered[match[1]:end]}
+
+		value := rendered[match[4]:match[5]]
+		switch rendered[match[2]:match[3]] {
+		case "message":
+			index, err := strconv.Atoi(value)
+			if err != nil || index < 0 || index >= count {
+				return nil, fmt.Errorf("invalid message separator %q", value)
+			}
+			part.index = index
+			last = index
+		case "role":
+			// Only FewShot inserts messages and only with these roles
+			if value != "user" && value != "assistant" {
+				return nil, fmt.Errorf("invalid role separator %q", value)
+			}
+			part.role = value
+		case "resume":
+			if last < 0 {
+				continue
+			}
+			part.index = last
+			part.resumed = true
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// newMessageError finds the message of an error of the joined template by the line
+// in the error
+func newMessageError(err error, starts []int) error {
+	match := templateLine.FindStringSubmatch(err.Error())
+	if match == nil || len(starts) == 0 {
+		return err
+	}
+
+	var line int
+	fmt.Sscanf(match[1], "%d", &line)
+
+	index := sort.SearchInts(starts, line+1) - 1
+	if index < 0 {
+		index = 0
+	}
+	return &MessageError{Index: index, Line: line - starts[index] + 1, Err: err}
+}
@@ -0,0 +1,232 @@
+package executor
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	wikilinkRegex = regexp.MustCompile(`(!?)\[\[([^\[\]|#^]*)([#^][^\[\]|]*)?(?:\|([^\[\]]*))?\]\]`)
+	tagRegex      = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]*[\p{L}_/-][\p{L}\p{N}_/-]*)`)
+	commentRegex  = regexp.MustCompile(`(?s)%%.*?%%`)
+	calloutRegex  = regexp.MustCompile(`(?m)^((?:>\s*)+)\[![^\]]+\][+-]?\s*`)
+	fenceRegex    = regexp.MustCompile("(?s)```.*?```")
+)
+
+// vault is an index of all markdown notes below the root
+type vault struct {
+	notes []string
+}
+
+// vault returns the lazily built note index of the working directory
+func (e *Env) vault() *vault {
+	if e.notes == nil {
+		e.notes = &vault{notes: e.Glob("**/*.md")}
+	}
+	return e.notes
+}
+
+// resolve finds the note a wikilink target points to. Like obsidian the target can
+// be a note name or a (partial) path, with or without extension.
+func (v *vault) resolve(target string) (string, bool) {
+	target = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(target, ".md")))
+	if target == "" {
+		return "", false
+	}
+
+	var best string
+	for _, note := range v.notes {
+		name := strings.ToLower(strings.TrimSuffix(note, ".md"))
+		if name != target && !strings.HasSuffix(name, "/"+target) {
+			continue
+		}
+
+		// Prefer the shortest path, obsidian's resolution for duplicate names
+		if best == "" || len(note) < len(best) {
+			best = note
+		}
+	}
+
+	return best, best != ""
+}
+
+// Note reads the note a wikilink target points to, e.g. "Red Dragon" or
+// "monsters/Red Dragon". With depth > 0 notes linked from it are appended up to
+// the given depth. Obsidian specific syntax is removed.
+func (e *Env) Note(target string, depth int) string {
+	note, ok := e.vault().resolve(target)
+	if !ok {
+		panic(fmt.Errorf("note %q not found", target))
+	}
+
+	visited := map[string]bool{note: true}
+	queue := []string{note}
+
+	var result strings.Builder
+	for level := 0; level <= depth && len(queue) > 0; level++ {
+		var next []string
+		for _, current := range queue {
+			content, err := e.readFile(e.abs(current))
+			if err != nil {
+				panic(err)
+			}
+			body := RemoveFrontmatter(current, string(content))
+
+			if current != note {
+				result.WriteString(fmt.Sprintf("\n\n====== Linked Note: %s\n", strings.TrimSuffix(path.Base(current), ".md")))
+			}
+			result.WriteString(StripObsidian(body))
+
+			for _, link := range Wikilinks(body) {
+				if linked, ok := e.vault().resolve(link); ok && !visited[linked] {
+					visited[linked] = true
+					next = append(next, linked)
+				}
+			}
+		}
+		queue = next
+	}
+
+	return result.String()
+}
+
+// Tagged returns the paths of all notes that have the tag, either inline or in the
+// tags field of the frontmatter. Nested tags like "#monster/undead" match "#monster" too.
+func (e *Env) Tagged(tag string) []string {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+
+	var notes []string
+	for _, note := range e.vault().notes {
+		content, err := e.readFile(e.abs(note))
+		if err != nil {
+			continue
+		}
+
+		for _, noteTag := range Tags(note, string(content)) {
+			noteTag = strings.ToLower(noteTag)
+			if noteTag == tag || strings.HasPrefix(noteTag, tag+"/") {
+				notes = append(notes, note)
+				break
+			}
+		}
+	}
+	return notes
+}
+
+// SampleTag reads count random notes with the tag and appends them as a string.
+// If meta is set the note name is included.
+func (e *Env) SampleTag(tag string, count int, meta bool) string {
+	notes := e.Tagged(tag)
+	if count > len(notes) {
+		count = len(notes)
+	}
+
+	e.rng.Shuffle(len(notes), func(i, j int) {
+		notes[i], notes[j] = notes[j], notes[i]
+	})
+
+	var result strings.Builder
+	for _, note := range notes[:count] {
+		content, err := e.readFile(e.abs(note))
+		if err != nil {
+			result.WriteString(fmt.Sprintf("Error reading file %s: %v\n", note, err))
+			continue
+		}
+
+		if meta {
+			result.WriteString(fmt.Sprintf("====== File: %s\n", note))
+		}
+		result.WriteString(StripObsidian(RemoveFrontmatter(note, string(content))))
+		result.WriteString("\n\n")
+	}
+
+	return result.String()
+}
+
+// Backlinks returns the paths of all notes that link to the note
+func (e *Env) Backlinks(target string) []string {
+	v := e.vault()
+	note, ok := v.resolve(target)
+	if !ok {
+		panic(fmt.Errorf("note %q not found", target))
+	}
+
+	var backlinks []string
+	for _, other := range v.notes {
+		if other == note {
+			continue
+		}
+
+		content, err := e.readFile(e.abs(other))
+		if err != nil {
+			continue
+		}
+
+		for _, link := range Wikilinks(string(content)) {
+			if linked, ok := v.resolve(link); ok && linked == note {
+				backlinks = append(backlinks, other)
+				break
+			}
+		}
+	}
+	return backlinks
+}
+
+// Wikilinks returns the targets of all wikilinks and embeds in the content
+func Wikilinks(content string) []string {
+	var links []string
+	for _, match := range wikilinkRegex.FindAllStringSubmatch(content, -1) {
+		if match[2] != "" {
+			links = append(links, match[2])
+		}
+	}
+	return links
+}
+
+// Tags returns the inline tags and the tags of the frontmatter of a note without the leading "#"
+func Tags(file string, content string) []string {
+	meta, body, _ := ParseFrontmatter(file, content)
+
+	var tags []string
+	for _, key := range []string{"tags", "tag"} {
+		switch value := meta[key].(type) {
+		case string:
+			for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+				tags = append(tags, strings.TrimPrefix(tag, "#"))
+			}
+		case []any:
+			for _, tag := range value {
+				tags = append(tags, strings.TrimPrefix(fmt.Sprint(tag), "#"))
+			}
+		}
+	}
+
+	body = fenceRegex.ReplaceAllString(body, "")
+	for _, match := range tagRegex.FindAllStringSubmatch(body, -1) {
+		tags = append(tags, match[1])
+	}
+
+	return tags
+}
+
+// StripObsidian removes obsidian specific syntax: embeds and comments are removed,
+// wikilinks are replaced by their text and callouts are turned into plain quotes.
+func StripObsidian(content string) string {
+	content = commentRegex.ReplaceAllString(content, "")
+	content = calloutRegex.ReplaceAllString(content, "$1")
+	content = wikilinkRegex.ReplaceAllStringFunc(content, func(link string) string {
+		match := wikilinkRegex.FindStringSubmatch(link)
+		switch {
+		case match[1] == "!":
+			return ""
+		case match[4] != "":
+			return match[4]
+		case match[2] != "":
+			return strings.TrimSuffix(path.Base(match[2]), ".md")
+		}
+		return strings.TrimLeft(match[3], "#^")
+	})
+	return strings.TrimSpace(content)
+}
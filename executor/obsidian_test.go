@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripObsidian(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "wikilink", content: "A [[Red Dragon]] appears", want: "A Red Dragon appears"},
+		{name: "wikilink with alias", content: "A [[monsters/Red Dragon|dragon]] appears", want: "A dragon appears"},
+		{name: "wikilink with path", content: "See [[monsters/Lich]]", want: "See Lich"},
+		{name: "wikilink with heading", content: "See [[Lich#Lair]]", want: "See Lich"},
+		{name: "embed", content: "Map:\n![[cave.png]]\nEnd", want: "Map:\n\nEnd"},
+		{name: "comment", content: "Visible %%hidden\nstill hidden%% visible", want: "Visible  visible"},
+		{name: "callout", content: "> [!warning] Danger\n> Content", want: "> Danger\n> Content"},
+		{name: "foldable callout", content: "> [!tip]- Hint", want: "> Hint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, StripObsidian(tt.content))
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	content := "---\ntags:\n  - monster\n  - \"#cr/5\"\n---\n# Heading\nA #undead creature, issue #42.\n```\n#notatag\n```\n"
+	assert.Equal(t, []string{"monster", "cr/5", "undead"}, Tags("ghoul.md", content))
+	assert.Equal(t, []string{"a", "b"}, Tags("x.md", "---\ntags: a, b\n---\n"))
+}
+
+func TestVault(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"monsters/Red Dragon.md": "---\ntags: [monster/dragon]\n---\nLives in [[Volcano]]. %%secret%%",
+		"monsters/Ghoul.md":      "A #monster/undead serving the [[Lich|lich]].",
+		"npcs/Lich.md":           "Rules the [[Volcano]].",
+		"places/Volcano.md":      "Hot. Home of [[Red Dragon]].",
+		"places/Tavern.md":       "#place",
+		".obsidian/app.md":       "[[Volcano]]",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	env, err := NewEnv(root, nil, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Lives in Volcano.", env.Note("red dragon", 0))
+	assert.Equal(t, "Lives in Volcano.\n\n====== Linked Note: Volcano\nHot. Home of Red Dragon.", env.Note("monsters/Red Dragon", 1))
+	assert.Panics(t, func() { env.Note("Beholder", 0) })
+
+	assert.ElementsMatch(t, []string{"monsters/Red Dragon.md", "monsters/Ghoul.md"}, env.Tagged("#monster"))
+	assert.Equal(t, []string{"monsters/Ghoul.md"}, env.Tagged("monster/undead"))
+	assert.Equal(t, "A #monster/undead serving the lich.\n\n", env.SampleTag("#monster/undead", 5, false))
+
+	assert.ElementsMatch(t, []string{"monsters/Red Dragon.md", "npcs/Lich.md"}, env.Backlinks("Volcano"))
+}
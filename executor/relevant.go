@@ -0,0 +1,73 @@
+package executor
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/bigjk/clai/index"
+)
+
+// Relevant reads the count files of the folder and its subdirectories that are most
+// relevant to the query, ranked by BM25, and appends them as a string.
+// If meta is set the file name is included.
+func (e *Env) Relevant(folder string, query string, count int, meta bool) string {
+	return e.RelevantMix(folder, query, count, 0, meta)
+}
+
+// RelevantMix reads the relevant files most relevant to the query plus random other
+// files for diversity and appends them as a string. If meta is set the file name is included.
+func (e *Env) RelevantMix(folder string, query string, relevant int, random int, meta bool) string {
+	bm25 := e.bm25(folder)
+
+	picked := map[string]bool{}
+	var files []string
+	for _, result := range bm25.Search(query, relevant) {
+		picked[result.Path] = true
+		files = append(files, result.Path)
+	}
+
+	var rest []string
+	for file := range bm25.Documents {
+		if !picked[file] {
+			rest = append(rest, file)
+		}
+	}
+	sort.Strings(rest)
+	if random > len(rest) {
+		random = len(rest)
+	}
+
+	e.rng.Shuffle(len(rest), func(i, j int) {
+		rest[i], rest[j] = rest[j], rest[i]
+	})
+	files = append(files, rest[:random]...)
+
+	// Show paths relative to the root like the other helpers
+	for i, file := range files {
+		if rel, err := filepath.Rel(e.Root, file); err == nil && isWithin(e.Root, file) {
+			files[i] = filepath.ToSlash(rel)
+		}
+	}
+
+	return e.writeFiles(files, meta)
+}
+
+// bm25 returns the up to date BM25 index of all files in the folder. The index is
+// cached in the cache directory and only changed files are indexed again.
+func (e *Env) bm25(folder string) *index.BM25 {
+	files := e.Glob(globEscaper.Replace(filepath.ToSlash(folder)) + "/**")
+
+	var cacheFile string
+	bm25 := index.NewBM25()
+	if e.CacheDir != "" {
+		cacheFile = index.CacheFile(e.CacheDir, "bm25", folder)
+		bm25 = index.LoadBM25(cacheFile)
+	}
+
+	if bm25.Update(files, e.readFile) && cacheFile != "" {
+		// A failing cache only costs time on the next run
+		_ = bm25.Save(cacheFile)
+	}
+
+	return bm25
+}
@@ -0,0 +1,34 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelevant(t *testing.T) {
+	for _, name := range []string{"vault", "vault [old]"} {
+		root := filepath.Join(t.TempDir(), name)
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "notes"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "notes", "ghoul.md"), []byte("A ghoul in the crypt"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "notes", "dragon.md"), []byte("A dragon"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "notes", "lich.md"), []byte("A lich"), 0644))
+
+		env, err := NewEnv(root, nil, false)
+		require.NoError(t, err)
+		env.CacheDir = t.TempDir()
+
+		folder, err := env.Resolve("notes")
+		require.NoError(t, err)
+		assert.Equal(t, "====== File: notes/ghoul.md\nA ghoul in the crypt\n\n", env.Relevant(folder, "ghoul", 1, true), name)
+
+		// The random files are picked from the rest
+		mixed := env.RelevantMix(folder, "ghoul", 1, 5, true)
+		assert.Contains(t, mixed, "notes/ghoul.md", name)
+		assert.Contains(t, mixed, "notes/dragon.md", name)
+		assert.Contains(t, mixed, "notes/lich.md", name)
+	}
+}
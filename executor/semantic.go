@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bigjk/clai/index"
+)
+
+// Semantic returns the k chunks of the files in the folder that are semantically most
+// similar to the query. The folder has to be indexed with "clai index build" first. If
+// the index was built with another embedding model, it is rebuilt.
+func (e *Env) Semantic(folder string, query string, k int) string {
+	if e.Embedder == nil {
+		panic(errors.New("semantic search needs an embedding model"))
+	}
+
+	vectors, err := index.LoadVectorIndex(index.CacheFile(e.CacheDir, "vectors", folder))
+	if err != nil {
+		panic(fmt.Errorf("no semantic index for %s found, create it with \"clai index build %s\"", folder, folder))
+	}
+
+	// The vectors of different models can't be compared
+	if model := index.EmbedModel(e.Embedder); model != "" && model != vectors.Model {
+		if vectors, _, err = e.indexFolder(folder, model, vectors.ChunkSize); err != nil {
+			panic(fmt.Errorf("error rebuilding the semantic index of %s for model %s: %w", folder, model, err))
+		}
+	}
+
+	embedding, err := e.Embedder.Embed([]string{query})
+	if err != nil {
+		panic(fmt.Errorf("error embedding query: %w", err))
+	}
+	if len(embedding) == 0 {
+		panic(errors.New("error embedding query: no embedding returned"))
+	}
+	if len(vectors.Chunks) > 0 && len(vectors.Chunks[0].Vector) != len(embedding[0]) {
+		panic(fmt.Errorf("the semantic index of %s was built with another embedding model, rebuild it with \"clai index build %s\"", folder, folder))
+	}
+
+	var result strings.Builder
+	found := 0
+	for _, hit := range vectors.Search(embedding[0], -1) {
+		if found >= k {
+			break
+		}
+
+		// Chunks of files that are no longer allowed or were deleted are skipped
+		if _, err := os.Stat(hit.Path); err != nil || e.Check(hit.Path) != nil {
+			continue
+		}
+		found++
+
+		name := hit.Path
+		if rel, err := filepath.Rel(e.Root, hit.Path); err == nil && isWithin(e.Root, hit.Path) {
+			name = filepath.ToSlash(rel)
+		}
+
+		result.WriteString(fmt.Sprintf("====== File: %s (line %d)\n", name, hit.Line))
+		result.WriteString(hit.Text)
+		result.WriteString("\n\n")
+	}
+
+	return result.String()
+}
+
+// IndexFolder creates or updates the semantic index of the folder. It returns the number
+// of embedded chunks.
+func (e *Env) IndexFolder(folder string, model string, chunkSize int) (int, error) {
+	if e.Embedder == nil {
+		return 0, errors.New("indexing needs an embedding model")
+	}
+
+	folder, err := e.Resolve(folder)
+	if err != nil {
+		return 0, err
+	}
+
+	_, embedded, err := e.indexFolder(folder, model, chunkSize)
+	return embedded, err
+}
+
+// indexFolder updates and saves the index of the resolved folder. The index is
+// rebuilt if the model or the chunk size changed.
+func (e *Env) indexFolder(folder string, model string, chunkSize int) (*index.VectorIndex, int, error) {
+	cacheFile := index.CacheFile(e.CacheDir, "vectors", folder)
+	vectors, err := index.LoadVectorIndex(cacheFile)
+	if err != nil || vectors.Model != model || vectors.ChunkSize != chunkSize {
+		vectors = index.NewVectorIndex(model, chunkSize)
+	}
+
+	files, err := e.glob(globEscaper.Replace(filepath.ToSlash(folder))+"/**", nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	embedded, err := vectors.Update(files, e.readFile, e.Embedder)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return vectors, embedded, vectors.Save(cacheFile)
+}
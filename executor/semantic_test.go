@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bigjk/clai/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wordEmbedder embeds texts as counts of a few words
+type wordEmbedder struct {
+	model string
+	texts int
+}
+
+func (w *wordEmbedder) Embed(texts []string) ([][]float32, error) {
+	w.texts += len(texts)
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		for _, word := range []string{"ghoul", "dragon", "crypt"} {
+			vectors[i] = append(vectors[i], float32(strings.Count(text, word)))
+		}
+	}
+	return vectors, nil
+}
+
+func (w *wordEmbedder) EmbedModel() string {
+	return w.model
+}
+
+func TestSemantic(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "vault [old]")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "notes"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes", "ghoul.md"), []byte("A ghoul in the crypt"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes", "dragon.md"), []byte("A dragon"), 0644))
+
+	env, err := NewEnv(root, nil, false)
+	require.NoError(t, err)
+	env.CacheDir = t.TempDir()
+	env.Embedder = &wordEmbedder{model: "a"}
+
+	embedded, err := env.IndexFolder("notes", "a", 100)
+	require.NoError(t, err)
+	assert.Equal(t, 2, embedded)
+
+	folder, err := env.Resolve("notes")
+	require.NoError(t, err)
+	assert.Equal(t, "====== File: notes/ghoul.md (line 1)\nA ghoul in the crypt\n\n", env.Semantic(folder, "ghoul", 1))
+
+	// Chunks of deleted files are skipped
+	require.NoError(t, os.Remove(filepath.Join(root, "notes", "ghoul.md")))
+	assert.Equal(t, "====== File: notes/dragon.md (line 1)\nA dragon\n\n", env.Semantic(folder, "ghoul", 1))
+
+	// An index of another model is rebuilt
+	embedder := &wordEmbedder{model: "b"}
+	env.Embedder = embedder
+	assert.Contains(t, env.Semantic(folder, "dragon", 1), "notes/dragon.md")
+	assert.Equal(t, 2, embedder.texts)
+
+	vectors, err := index.LoadVectorIndex(index.CacheFile(env.CacheDir, "vectors", folder))
+	require.NoError(t, err)
+	assert.Equal(t, "b", vectors.Model)
+	assert.Len(t, vectors.Chunks, 1)
+}
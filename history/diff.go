@@ -0,0 +1,47 @@
+package history
+
+import (
+	"strings"
+)
+
+// Diff returns a line based diff of two texts. Removed lines are prefixed with "- ",
+// added lines with "+ " and unchanged lines with "  ".
+func Diff(a, b string) string {
+	linesA := strings.Split(a, "\n")
+	linesB := strings.Split(b, "\n")
+
+	// Longest common subsequence table, lcs[i][j] is the length of the lcs of
+	// linesA[i:] and linesB[j:]
+	lcs := make([][]int, len(linesA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(linesB)+1)
+	}
+	for i := len(linesA) - 1; i >= 0; i-- {
+		for j := len(linesB) - 1; j >= 0; j-- {
+			if linesA[i] == linesB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var result strings.Builder
+	i, j := 0, 0
+	for i < len(linesA) || j < len(linesB) {
+		switch {
+		case i < len(linesA) && j < len(linesB) && linesA[i] == linesB[j]:
+			result.WriteString("  " + linesA[i] + "\n")
+			i++
+			j++
+		case i < len(linesA) && (j == len(linesB) || lcs[i+1][j] >= lcs[i][j+1]):
+			result.WriteString("- " + linesA[i] + "\n")
+			i++
+		default:
+			result.WriteString("+ " + linesB[j] + "\n")
+			j++
+		}
+	}
+
+	return result.String()
+}
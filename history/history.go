@@ -0,0 +1,213 @@
+package history
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bigjk/clai/runner"
+)
+
+// Entry is a recorded invocation of a workflow with all its runs
+type Entry struct {
+	ID           string          `json:"id"`
+	Command      string          `json:"command"`
+	Workflow     string          `json:"workflow"`
+	WorkflowHash string          `json:"workflow_hash"`
+	WorkingDir   string          `json:"working_dir"`
+	CreatedAt    time.Time       `json:"created_at"`
+	Runs         []runner.Result `json:"runs"`
+}
+
+// Store keeps the history entries as one json file per entry in a directory
+type Store struct {
+	Dir string
+}
+
+// DefaultDir returns the default history directory inside the user config directory
+func DefaultDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "clai", "history")
+}
+
+// NewStore creates a store in the directory, or in the default directory if it is empty
+func NewStore(dir string) *Store {
+	if dir == "" {
+		dir = DefaultDir()
+	}
+	return &Store{Dir: dir}
+}
+
+// HashWorkflow returns the hash of the workflow content used to detect changed workflows
+func HashWorkflow(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// NewEntry creates an entry for a workflow file. The id is sortable by time.
+func NewEntry(command string, workflow string, content []byte, workingDir string) *Entry {
+	if abs, err := filepath.Abs(workflow); err == nil {
+		workflow = abs
+	}
+	if abs, err := filepath.Abs(workingDir); err == nil {
+		workingDir = abs
+	}
+
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+
+	now := time.Now()
+	return &Entry{
+		ID:           strings.Replace(now.Format("20060102-150405.000"), ".", "-", 1) + "-" + hex.EncodeToString(suffix),
+		Command:      command,
+		Workflow:     workflow,
+		WorkflowHash: HashWorkflow(content),
+		WorkingDir:   workingDir,
+		CreatedAt:    now,
+	}
+}
+
+// Save writes the entry to the store
+func (s *Store) Save(entry *Entry) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return fmt.Errorf("error creating history directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding history entry: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(s.Dir, entry.ID+".json"), data, 0600); err != nil {
+		return fmt.Errorf("error writing history entry: %w", err)
+	}
+	return nil
+}
+
+// IDs returns the ids of all entries, oldest first
+func (s *Store) IDs() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading history directory: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// List returns the newest entries first. If limit is > 0 at most limit entries are returned.
+func (s *Store) List(limit int) ([]*Entry, error) {
+	ids, err := s.IDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for i := len(ids) - 1; i >= 0 && (limit <= 0 || len(entries) < limit); i-- {
+		entry, err := s.load(ids[i])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Get returns the entry with the id. A unique prefix of the id or "last" for the
+// newest entry are accepted too.
+func (s *Store) Get(id string) (*Entry, error) {
+	ids, err := s.IDs()
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "last" {
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("history is empty")
+		}
+		return s.load(ids[len(ids)-1])
+	}
+
+	var matches []string
+	for _, candidate := range ids {
+		if candidate == id {
+			return s.load(candidate)
+		}
+		if strings.HasPrefix(candidate, id) {
+			matches = append(matches, candidate)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("history entry %q not found", id)
+	case 1:
+		return s.load(matches[0])
+	}
+	return nil, fmt.Errorf("history entry %q is ambiguous, matches %d entries", id, len(matches))
+}
+
+// Resolve returns the run a reference like "<id>" or "<id>:<run>" points to. Without
+// a run number the first run is used.
+func (s *Store) Resolve(ref string) (*Entry, *runner.Result, error) {
+	id, run := ref, 1
+	if i := strings.LastIndex(ref, ":"); i >= 0 {
+		n, err := strconv.Atoi(ref[i+1:])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid run number in %q", ref)
+		}
+		id, run = ref[:i], n
+	}
+
+	entry, err := s.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := entry.Run(run)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entry, result, nil
+}
+
+// Run returns the run with the index, starting at 1
+func (e *Entry) Run(index int) (*runner.Result, error) {
+	if index < 1 || index > len(e.Runs) {
+		return nil, fmt.Errorf("entry %s has no run %d, it has %d runs", e.ID, index, len(e.Runs))
+	}
+	return &e.Runs[index-1], nil
+}
+
+func (s *Store) load(id string) (*Entry, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, id+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading history entry: %w", err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("error parsing history entry %s: %w", id, err)
+	}
+	return &entry, nil
+}
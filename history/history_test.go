@@ -0,0 +1,67 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	ids, err := store.IDs()
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	_, err = store.Get("last")
+	assert.Error(t, err)
+
+	first := NewEntry("run", "workflow.md", []byte("# CLAI::USER\nHi"), ".")
+	first.ID = "20250101-120000-aaaaaa"
+	first.Runs = []runner.Result{{Seed: 1, Messages: []ai.Message{{Role: "user", Content: "Hi"}}, Response: "Hello"}}
+	require.NoError(t, store.Save(first))
+
+	second := NewEntry("run_multiple", "workflow.md", []byte("changed"), ".")
+	second.ID = "20250102-120000-bbbbbb"
+	second.CreatedAt = first.CreatedAt.Add(time.Hour)
+	second.Runs = []runner.Result{{Seed: 2}, {Seed: 3, Response: "Second"}}
+	require.NoError(t, store.Save(second))
+
+	assert.NotEqual(t, first.WorkflowHash, second.WorkflowHash)
+
+	entries, err := store.List(1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, second.ID, entries[0].ID)
+
+	last, err := store.Get("last")
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, last.ID)
+
+	loaded, err := store.Get("20250101")
+	require.NoError(t, err)
+	assert.Equal(t, first.Runs, loaded.Runs)
+
+	_, err = store.Get("2025")
+	assert.ErrorContains(t, err, "ambiguous")
+	_, err = store.Get("2024")
+	assert.ErrorContains(t, err, "not found")
+
+	_, result, err := store.Resolve("20250102:2")
+	require.NoError(t, err)
+	assert.Equal(t, "Second", result.Response)
+
+	_, _, err = store.Resolve("20250102:3")
+	assert.Error(t, err)
+	_, _, err = store.Resolve("20250102:x")
+	assert.Error(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	assert.Equal(t, "  a\n- b\n+ x\n  c\n+ d\n", Diff("a\nb\nc", "a\nx\nc\nd"))
+	assert.Equal(t, "  same\n", Diff("same", "same"))
+}
@@ -0,0 +1,202 @@
+package index
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// stopwords are common english words that are not indexed
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "he": true, "in": true, "is": true, "it": true, "its": true,
+	"of": true, "on": true, "or": true, "she": true, "that": true, "the": true, "their": true, "they": true,
+	"this": true, "to": true, "was": true, "were": true, "will": true, "with": true,
+}
+
+// Document is a single indexed file
+type Document struct {
+	ModTime int64          `json:"mod_time"`
+	Size    int64          `json:"size"`
+	Length  int            `json:"length"`
+	Terms   map[string]int `json:"terms"`
+}
+
+// BM25 is a lexical search index over files
+type BM25 struct {
+	Documents map[string]*Document `json:"documents"`
+}
+
+// Result is a search hit
+type Result struct {
+	Path  string
+	Score float64
+}
+
+// NewBM25 creates an empty index
+func NewBM25() *BM25 {
+	return &BM25{Documents: map[string]*Document{}}
+}
+
+// LoadBM25 loads an index from a file. If the file doesn't exist or is broken an empty
+// index is returned.
+func LoadBM25(file string) *BM25 {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return NewBM25()
+	}
+
+	index := NewBM25()
+	if err := json.Unmarshal(content, index); err != nil || index.Documents == nil {
+		return NewBM25()
+	}
+	return index
+}
+
+// Save writes the index to a file
+func (b *BM25) Save(file string) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(file, data)
+}
+
+// Update brings the index up to date with the given files. Files whose modification
+// time and size didn't change are not read again, files that are not in the list are
+// removed. It reports if the index changed.
+func (b *BM25) Update(files []string, read func(string) ([]byte, error)) bool {
+	changed := false
+
+	keep := map[string]bool{}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		keep[file] = true
+
+		if doc, ok := b.Documents[file]; ok && doc.ModTime == info.ModTime().UnixNano() && doc.Size == info.Size() {
+			continue
+		}
+
+		content, err := read(file)
+		if err != nil {
+			delete(keep, file)
+			continue
+		}
+
+		b.Add(file, string(content))
+		b.Documents[file].ModTime = info.ModTime().UnixNano()
+		b.Documents[file].Size = info.Size()
+		changed = true
+	}
+
+	for file := range b.Documents {
+		if !keep[file] {
+			delete(b.Documents, file)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// Add indexes the content of a file
+func (b *BM25) Add(file string, content string) {
+	tokens := Tokenize(content)
+
+	doc := &Document{
+		Length: len(tokens),
+		Terms:  map[string]int{},
+	}
+	for _, token := range tokens {
+		doc.Terms[token]++
+	}
+
+	b.Documents[file] = doc
+}
+
+// Search returns up to k documents ranked by their BM25 score for the query.
+// Documents that don't contain any query term are not returned.
+func (b *BM25) Search(query string, k int) []Result {
+	if len(b.Documents) == 0 {
+		return nil
+	}
+
+	totalLength := 0
+	for _, doc := range b.Documents {
+		totalLength += doc.Length
+	}
+	avgLength := float64(totalLength) / float64(len(b.Documents))
+	if avgLength == 0 {
+		avgLength = 1
+	}
+
+	terms := map[string]bool{}
+	for _, term := range Tokenize(query) {
+		terms[term] = true
+	}
+
+	scores := map[string]float64{}
+	for term := range terms {
+		df := 0
+		for _, doc := range b.Documents {
+			if doc.Terms[term] > 0 {
+				df++
+			}
+		}
+		if df == 0 {
+			continue
+		}
+
+		n := float64(len(b.Documents))
+		idf := math.Log((n-float64(df)+0.5)/(float64(df)+0.5) + 1)
+		for file, doc := range b.Documents {
+			tf := float64(doc.Terms[term])
+			if tf == 0 {
+				continue
+			}
+			scores[file] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(doc.Length)/avgLength))
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for file, score := range scores {
+		results = append(results, Result{Path: file, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].Path < results[j].Path
+		}
+		return results[i].Score > results[j].Score
+	})
+
+	if k >= 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// Tokenize splits text into lower case terms, dropping stopwords and single characters
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, field := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(field)) < 2 || stopwords[field] {
+			continue
+		}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
@@ -0,0 +1,66 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"red", "dragon", "breathes", "fire", "cr", "17"}, Tokenize("The Red-Dragon breathes FIRE (CR 17)!"))
+}
+
+func TestBM25Search(t *testing.T) {
+	bm25 := NewBM25()
+	bm25.Add("dragon.md", "A huge red dragon that breathes fire and hoards gold.")
+	bm25.Add("ghoul.md", "An undead ghoul that feeds on corpses.")
+	bm25.Add("wyrm.md", "A dragon wyrmling, young and small.")
+	bm25.Add("tavern.md", "A cozy tavern with warm fire.")
+
+	results := bm25.Search("fire breathing dragon", 10)
+	require.Len(t, results, 3)
+	assert.Equal(t, "dragon.md", results[0].Path)
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	assert.Len(t, bm25.Search("dragon", 1), 1)
+	assert.Empty(t, bm25.Search("beholder", 10))
+}
+
+func TestBM25UpdateAndCache(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.md")
+	b := filepath.Join(dir, "b.md")
+	require.NoError(t, os.WriteFile(a, []byte("red dragon"), 0644))
+	require.NoError(t, os.WriteFile(b, []byte("undead ghoul"), 0644))
+
+	reads := 0
+	read := func(file string) ([]byte, error) {
+		reads++
+		return os.ReadFile(file)
+	}
+
+	bm25 := NewBM25()
+	assert.True(t, bm25.Update([]string{a, b}, read))
+	assert.Equal(t, 2, reads)
+
+	cache := filepath.Join(dir, "cache", "index.json")
+	require.NoError(t, bm25.Save(cache))
+
+	loaded := LoadBM25(cache)
+	assert.False(t, loaded.Update([]string{a, b}, read))
+	assert.Equal(t, 2, reads)
+
+	// Changed files are indexed again, removed files dropped
+	require.NoError(t, os.WriteFile(a, []byte("blue dragon with lightning"), 0644))
+	require.NoError(t, os.Chtimes(a, time.Now(), time.Now().Add(time.Hour)))
+	assert.True(t, loaded.Update([]string{a}, read))
+	assert.Equal(t, 3, reads)
+	assert.Len(t, loaded.Documents, 1)
+	assert.Equal(t, a, loaded.Search("lightning", 1)[0].Path)
+
+	assert.Empty(t, LoadBM25(filepath.Join(dir, "missing.json")).Documents)
+}
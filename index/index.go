@@ -0,0 +1,38 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// CacheFile returns the file the index of the given kind for a folder is stored in
+func CacheFile(cacheDir string, kind string, folder string) string {
+	hash := sha256.Sum256([]byte(folder))
+	return filepath.Join(cacheDir, kind, hex.EncodeToString(hash[:8])+".json")
+}
+
+// writeFileAtomic writes to a temporary file first, so that concurrent runs never
+// read a partially written index.
+func writeFileAtomic(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
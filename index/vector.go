@@ -0,0 +1,265 @@
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// embedBatchSize is the number of chunks embedded per request
+const embedBatchSize = 64
+
+// Embedder turns texts into embedding vectors. ai.Client implements it.
+type Embedder interface {
+	Embed(texts []string) ([][]float32, error)
+}
+
+// ModelEmbedder is an Embedder that knows its model. Indexes built with another
+// model can't be searched with its vectors.
+type ModelEmbedder interface {
+	Embedder
+	EmbedModel() string
+}
+
+// EmbedModel returns the model of the embedder, or an empty string if it is unknown
+func EmbedModel(embedder Embedder) string {
+	if e, ok := embedder.(ModelEmbedder); ok {
+		return e.EmbedModel()
+	}
+	return ""
+}
+
+// Chunk is a part of a file with its embedding
+type Chunk struct {
+	Path   string    `json:"path"`
+	Line   int       `json:"line"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// FileState is used to detect changed files
+type FileState struct {
+	ModTime int64 `json:"mod_time"`
+	Size    int64 `json:"size"`
+}
+
+// VectorIndex is a semantic search index over chunks of files
+type VectorIndex struct {
+	Model     string               `json:"model"`
+	ChunkSize int                  `json:"chunk_size"`
+	Files     map[string]FileState `json:"files"`
+	Chunks    []Chunk              `json:"chunks"`
+}
+
+// ChunkResult is a search hit
+type ChunkResult struct {
+	Chunk
+	Score float64
+}
+
+// NewVectorIndex creates an empty index for the embedding model. Files are split into
+// chunks of about chunkSize words.
+func NewVectorIndex(model string, chunkSize int) *VectorIndex {
+	return &VectorIndex{
+		Model:     model,
+		ChunkSize: chunkSize,
+		Files:     map[string]FileState{},
+	}
+}
+
+// LoadVectorIndex loads an index from a file
+func LoadVectorIndex(file string) (*VectorIndex, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var index VectorIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, err
+	}
+	if index.Files == nil {
+		index.Files = map[string]FileState{}
+	}
+	return &index, nil
+}
+
+// Save writes the index to a file
+func (v *VectorIndex) Save(file string) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(file, data)
+}
+
+// Update brings the index up to date with the given files. Only new and changed files
+// are chunked and embedded, files not in the list are removed. It returns the number
+// of embedded chunks.
+func (v *VectorIndex) Update(files []string, read func(string) ([]byte, error), embedder Embedder) (int, error) {
+	keep := map[string]bool{}
+	var changed []string
+	states := map[string]FileState{}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		keep[file] = true
+
+		state := FileState{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+		if old, ok := v.Files[file]; !ok || old != state {
+			changed = append(changed, file)
+			states[file] = state
+		}
+	}
+
+	var pending []Chunk
+	for _, file := range changed {
+		content, err := read(file)
+		if err != nil {
+			delete(keep, file)
+			continue
+		}
+
+		for _, chunk := range ChunkText(string(content), v.ChunkSize) {
+			chunk.Path = file
+			pending = append(pending, chunk)
+		}
+	}
+
+	for i := 0; i < len(pending); i += embedBatchSize {
+		batch := pending[i:min(i+embedBatchSize, len(pending))]
+
+		texts := make([]string, len(batch))
+		for j := range batch {
+			texts[j] = batch[j].Text
+		}
+
+		vectors, err := embedder.Embed(texts)
+		if err != nil {
+			return 0, err
+		}
+		if len(vectors) != len(batch) {
+			return 0, errors.New("embedder returned wrong number of vectors")
+		}
+		for j := range batch {
+			batch[j].Vector = normalize(vectors[j])
+		}
+	}
+
+	// Replace the chunks of changed and removed files
+	isChanged := map[string]bool{}
+	for _, file := range changed {
+		isChanged[file] = true
+	}
+	chunks := pending
+	for _, chunk := range v.Chunks {
+		if keep[chunk.Path] && !isChanged[chunk.Path] {
+			chunks = append(chunks, chunk)
+		}
+	}
+	v.Chunks = chunks
+
+	for file := range v.Files {
+		if !keep[file] {
+			delete(v.Files, file)
+		}
+	}
+	for file, state := range states {
+		if keep[file] {
+			v.Files[file] = state
+		}
+	}
+
+	return len(pending), nil
+}
+
+// Search returns the k chunks most similar to the query vector
+func (v *VectorIndex) Search(query []float32, k int) []ChunkResult {
+	query = normalize(query)
+
+	results := make([]ChunkResult, 0, len(v.Chunks))
+	for _, chunk := range v.Chunks {
+		results = append(results, ChunkResult{Chunk: chunk, Score: dot(query, chunk.Vector)})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if k >= 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// ChunkText splits text into chunks of about size words. Paragraphs are kept together
+// as long as they fit into a chunk.
+func ChunkText(text string, size int) []Chunk {
+	if size <= 0 {
+		size = 200
+	}
+
+	var chunks []Chunk
+	var current []string
+	words := 0
+	start := 1
+
+	flush := func(next int) {
+		content := strings.TrimSpace(strings.Join(current, "\n"))
+		if content != "" {
+			chunks = append(chunks, Chunk{Line: start, Text: content})
+		}
+		current = nil
+		words = 0
+		start = next
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		lineWords := len(strings.Fields(line))
+
+		// Start a new chunk at a paragraph break if the chunk is full enough, or
+		// anywhere if it would get too large.
+		if (strings.TrimSpace(line) == "" && words >= size/2) || (words > 0 && words+lineWords > size) {
+			flush(i + 1)
+		}
+		if len(current) == 0 && strings.TrimSpace(line) == "" {
+			start = i + 2
+			continue
+		}
+
+		current = append(current, line)
+		words += lineWords
+	}
+	flush(0)
+
+	return chunks
+}
+
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, x := range vector {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return vector
+	}
+
+	norm := float32(math.Sqrt(sum))
+	normalized := make([]float32, len(vector))
+	for i, x := range vector {
+		normalized[i] = x / norm
+	}
+	return normalized
+}
+
+func dot(a []float32, b []float32) float64 {
+	var sum float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,106 @@
+package index
+
+import (
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEmbedder is a deterministic embedder that hashes the terms of a text into a
+// bag of words vector.
+type fakeEmbedder struct {
+	calls int
+	texts int
+}
+
+func (f *fakeEmbedder) Embed(texts []string) ([][]float32, error) {
+	f.calls++
+	f.texts += len(texts)
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, 64)
+		for _, term := range Tokenize(text) {
+			h := fnv.New32a()
+			h.Write([]byte(term))
+			vector[h.Sum32()%64]++
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func TestChunkText(t *testing.T) {
+	text := "# Title\n\nfirst paragraph with five words\n\nsecond paragraph with five words\nand a longer line of words\n"
+
+	chunks := ChunkText(text, 8)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, Chunk{Line: 1, Text: "# Title\n\nfirst paragraph with five words"}, chunks[0])
+	assert.Equal(t, Chunk{Line: 5, Text: "second paragraph with five words"}, chunks[1])
+	assert.Equal(t, 6, chunks[2].Line)
+
+	assert.Len(t, ChunkText(text, 200), 1)
+	assert.Empty(t, ChunkText("\n\n", 200))
+}
+
+func TestVectorIndex(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"dragon.md": "A red dragon breathes fire over the mountains.",
+		"ghoul.md":  "An undead ghoul lurks in the crypt.",
+		"tavern.md": "The tavern serves ale and bread.",
+	}
+
+	var paths []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		paths = append(paths, path)
+	}
+
+	embedder := &fakeEmbedder{}
+	vectors := NewVectorIndex("fake", 200)
+
+	embedded, err := vectors.Update(paths, os.ReadFile, embedder)
+	require.NoError(t, err)
+	assert.Equal(t, 3, embedded)
+	assert.Equal(t, 1, embedder.calls)
+
+	query, _ := embedder.Embed([]string{"undead crypt"})
+	results := vectors.Search(query[0], 1)
+	require.Len(t, results, 1)
+	assert.Equal(t, filepath.Join(dir, "ghoul.md"), results[0].Path)
+
+	cache := filepath.Join(dir, "cache", "vectors.json")
+	require.NoError(t, vectors.Save(cache))
+	loaded, err := LoadVectorIndex(cache)
+	require.NoError(t, err)
+
+	// Unchanged files are not embedded again
+	embedder.texts = 0
+	embedded, err = loaded.Update(paths, os.ReadFile, embedder)
+	require.NoError(t, err)
+	assert.Equal(t, 0, embedded)
+
+	// Changed files are embedded again, removed files are dropped
+	ghoul := filepath.Join(dir, "ghoul.md")
+	require.NoError(t, os.WriteFile(ghoul, []byte("A ghoul that now sings in the tavern choir."), 0644))
+	require.NoError(t, os.Chtimes(ghoul, time.Now(), time.Now().Add(time.Hour)))
+	embedded, err = loaded.Update([]string{ghoul, filepath.Join(dir, "dragon.md")}, os.ReadFile, embedder)
+	require.NoError(t, err)
+	assert.Equal(t, 1, embedded)
+	assert.Len(t, loaded.Chunks, 2)
+	assert.Len(t, loaded.Files, 2)
+
+	query, _ = embedder.Embed([]string{"choir sings"})
+	assert.True(t, strings.Contains(loaded.Search(query[0], 1)[0].Text, "choir"))
+
+	_, err = LoadVectorIndex(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
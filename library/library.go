@@ -0,0 +1,168 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bigjk/clai/templating"
+)
+
+// Library finds workflows by name in a list of directories. The name of a workflow
+// is its path relative to the directory without the extension, e.g. "ttrpg/monsters".
+// If several directories contain the same name, the first one wins, and in a
+// directory the first extension of templating.Extensions.
+type Library struct {
+	Dirs []string
+}
+
+// Entry is a workflow of the library
+type Entry struct {
+	Name string
+	File string
+	Meta templating.Meta
+	// Err is set if the workflow can't be parsed
+	Err error
+}
+
+// New creates a library of the directories. A leading ~ is replaced by the home
+// directory, relative directories are resolved against base.
+func New(base string, dirs []string) *Library {
+	home, _ := os.UserHomeDir()
+
+	l := &Library{}
+	for _, dir := range dirs {
+		if rest, ok := strings.CutPrefix(dir, "~"); ok && home != "" && (rest == "" || rest[0] == '/' || rest[0] == filepath.Separator) {
+			dir = filepath.Join(home, rest)
+		} else if !filepath.IsAbs(dir) {
+			dir = filepath.Join(base, dir)
+		}
+		l.Dirs = append(l.Dirs, dir)
+	}
+	return l
+}
+
+// Find returns the file of the workflow with the name
+func (l *Library) Find(name string) (string, error) {
+	name = filepath.FromSlash(name)
+	if templating.IsWorkflowFile(name) {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if name == "" || filepath.IsAbs(name) || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid workflow name %q", name)
+	}
+
+	for _, dir := range l.Dirs {
+		if file := findIn(dir, name); file != "" {
+			return file, nil
+		}
+	}
+
+	if len(l.Dirs) == 0 {
+		return "", fmt.Errorf("workflow %q not found, no workflow_dirs configured", name)
+	}
+	return "", fmt.Errorf("workflow %q not found in %s", name, strings.Join(l.Dirs, ", "))
+}
+
+// findIn returns the file of the workflow in the directory, or an empty string
+func findIn(dir string, name string) string {
+	for _, ext := range templating.Extensions {
+		file := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			return file
+		}
+	}
+	return ""
+}
+
+// List returns all workflows of the library sorted by name. Hidden files and
+// directories are skipped, missing directories are ignored.
+func (l *Library) List() ([]Entry, error) {
+	seen := map[string]bool{}
+	var entries []Entry
+
+	for _, dir := range l.Dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == dir && os.IsNotExist(err) {
+					return fs.SkipDir
+				}
+				return err
+			}
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !templating.IsWorkflowFile(path) {
+				return nil
+			}
+
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+			if seen[name] {
+				return nil
+			}
+			seen[name] = true
+
+			// Another extension of the same name may take precedence
+			if preferred := findIn(dir, strings.TrimSuffix(rel, filepath.Ext(rel))); preferred != "" {
+				path = preferred
+			}
+			entry := Entry{Name: name, File: path}
+			content, err := os.ReadFile(path)
+			if err == nil {
+				var workflow *templating.Workflow
+				if workflow, err = templating.ParseWorkflowFile(path, string(content)); err == nil {
+					entry.Meta = workflow.Meta
+				}
+			}
+			entry.Err = err
+			entries = append(entries, entry)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing workflows of %s: %w", dir, err)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// ArgsInput converts the arguments of the command line to the input of a workflow.
+// If the workflow declares inputs and every argument is "key=value" with a declared
+// key, the input is a JSON object of them. Otherwise the arguments are joined with
+// spaces.
+func ArgsInput(args []string, inputs []templating.Input) string {
+	if len(inputs) == 0 || len(args) == 0 {
+		return strings.Join(args, " ")
+	}
+
+	declared := map[string]bool{}
+	for _, input := range inputs {
+		declared[input.Name] = true
+	}
+
+	fields := map[string]string{}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || !declared[key] {
+			return strings.Join(args, " ")
+		}
+		fields[key] = value
+	}
+
+	data, _ := json.Marshal(fields)
+	return string(data)
+}
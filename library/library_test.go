@@ -0,0 +1,82 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bigjk/clai/templating"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibrary(t *testing.T) {
+	base := t.TempDir()
+	write := func(name, content string) {
+		file := filepath.Join(base, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	}
+
+	write("personal/monsters.md", "---\ndescription: Mine\n---\n# CLAI::USER\nHi")
+	write("shared/monsters.md", "---\ndescription: Shared\n---\n# CLAI::USER\nHi")
+	write("shared/ttrpg/npc.md", "---\ninputs: [name]\n---\n# CLAI::USER\n{{ .name }}")
+	write("shared/broken.md", "---\ninputs: [\n---\n")
+	write("shared/.hidden/secret.md", "# CLAI::USER\nHi")
+	write("shared/notes.txt", "not a workflow")
+	write("shared/tavern.yaml", "description: Taverns\nmessages:\n  - role: user\n    content: Hi")
+	write("shared/tavern.json", `{"description": "Ignored", "messages": []}`)
+
+	lib := New(base, []string{"personal", filepath.Join(base, "shared"), "missing"})
+	assert.Equal(t, filepath.Join(base, "personal"), lib.Dirs[0])
+
+	entries, err := lib.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	assert.Equal(t, "broken", entries[0].Name)
+	assert.Error(t, entries[0].Err)
+	assert.Equal(t, "monsters", entries[1].Name)
+	assert.Equal(t, "Mine", entries[1].Meta.Description)
+	assert.Equal(t, "tavern", entries[2].Name)
+	assert.Equal(t, "Taverns", entries[2].Meta.Description)
+	assert.Equal(t, "ttrpg/npc", entries[3].Name)
+	assert.Equal(t, []templating.Input{{Name: "name"}}, entries[3].Meta.Inputs)
+
+	file, err := lib.Find("monsters")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(base, "personal", "monsters.md"), file)
+
+	file, err = lib.Find("ttrpg/npc.md")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(base, "shared", "ttrpg", "npc.md"), file)
+
+	file, err = lib.Find("tavern")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(base, "shared", "tavern.yaml"), file)
+
+	for _, name := range []string{"missing", "../shared/monsters", "", "notes.txt"} {
+		_, err = lib.Find(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestArgsInput(t *testing.T) {
+	inputs := []templating.Input{{Name: "name"}, {Name: "cr"}}
+
+	tests := []struct {
+		args     []string
+		inputs   []templating.Input
+		expected string
+	}{
+		{args: []string{"A", "red", "dragon"}, inputs: inputs, expected: "A red dragon"},
+		{args: []string{"name=Ghoul", "cr=2"}, inputs: inputs, expected: `{"cr":"2","name":"Ghoul"}`},
+		{args: []string{"name=Ghoul", "size=large"}, inputs: inputs, expected: "name=Ghoul size=large"},
+		{args: []string{"name=Ghoul"}, expected: "name=Ghoul"},
+		{args: []string{"name=a=b"}, inputs: inputs, expected: `{"name":"a=b"}`},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, ArgsInput(tt.args, tt.inputs), tt.args)
+	}
+}
@@ -0,0 +1,370 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/bigjk/clai/executor"
+	"github.com/bigjk/clai/templating"
+	"gopkg.in/yaml.v3"
+)
+
+// Severities of issues
+const (
+	Error   = "error"
+	Warning = "warning"
+)
+
+// Issue is a problem found in a workflow
+type Issue struct {
+	Line     int
+	Severity string
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%d: %s: %s", i.Line, i.Severity, i.Message)
+}
+
+// roles are the valid roles of the role markers
+var roles = map[string]bool{"system": true, "user": true, "assistant": true}
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+var templateStart = regexp.MustCompile(`^(.*) started at [^:]*:(\d+)$`)
+
+var templateError = regexp.MustCompile(`^template: [^:]*:(\d+):(?:\d+:)? ?(.*)$`)
+
+// Lint checks a workflow without running it. Referenced files and folders are
+// resolved against the working directory. The issues are sorted by line.
+func Lint(content string, workingDir string) []Issue {
+	l := &linter{workingDir: workingDir, helpers: executor.Helpers()}
+
+	frontmatter, body := templating.SplitFrontmatter(content)
+	offset := templating.BodyOffset(content, body)
+	if offset > 0 {
+		var meta templating.Meta
+		if err := yaml.Unmarshal([]byte(frontmatter), &meta); err != nil {
+			l.add(2, Error, "invalid frontmatter: %v", err)
+		}
+	}
+
+	l.messages(body, offset)
+	return l.sorted()
+}
+
+// LintStructured checks a workflow in the YAML or JSON format like Lint
+func LintStructured(content string, workingDir string) []Issue {
+	l := &linter{workingDir: workingDir, helpers: executor.Helpers()}
+
+	doc, err := templating.ParseStructuredDocument(content)
+	if err != nil {
+		line := 1
+		if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+			fmt.Sscanf(match[1], "%d", &line)
+		}
+		l.add(line, Error, "%v", err)
+		return l.sorted()
+	}
+
+	var segments []segment
+	for _, msg := range doc.Messages {
+		if !roles[msg.Role] {
+			l.add(msg.Line, Error, "unknown role %q, expected system, user or assistant", msg.Role)
+		}
+		l.attachments(msg.Attachments, msg.Line)
+		if strings.TrimSpace(msg.Content) == "" {
+			l.add(msg.Line, Warning, "empty %s message is dropped", msg.Role)
+			continue
+		}
+		segments = append(segments, segment{content: strings.TrimSpace(msg.Content), line: msg.Line})
+	}
+	l.template(segments)
+
+	if len(doc.Messages) == 0 {
+		l.add(1, Error, "no messages found")
+	}
+	return l.sorted()
+}
+
+type linter struct {
+	workingDir string
+	helpers    map[string]reflect.Type
+	issues     []Issue
+	// lineOf returns the line of the file of a position in the parsed template
+	lineOf func(pos parse.Pos) int
+}
+
+func (l *linter) sorted() []Issue {
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		return l.issues[i].Line < l.issues[j].Line
+	})
+	return l.issues
+}
+
+func (l *linter) add(line int, severity string, format string, args ...any) {
+	l.issues = append(l.issues, Issue{Line: line, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+// messages checks the roles, attributes and templates of the sections of the body.
+// offset is the number of lines before the body.
+func (l *linter) messages(body string, offset int) {
+	var segments []segment
+	found := false
+	for _, section := range templating.ParseSections(body) {
+		if section.Preamble {
+			l.add(offset+section.ContentLine, Warning, "content before the first role marker is ignored")
+			continue
+		}
+
+		found = true
+		line := offset + section.Line
+		if !roles[section.Role] {
+			l.add(line, Error, "unknown role %q, expected SYSTEM, USER or ASSISTANT", strings.ToUpper(section.Role))
+		}
+		if msg, err := section.Message(); err != nil {
+			l.add(line, Error, "%v", err)
+		} else {
+			l.attachments(msg.Attachments, line)
+		}
+		if section.Content == "" {
+			l.add(line, Warning, "empty %s message is dropped", section.Role)
+			continue
+		}
+		segments = append(segments, segment{content: section.Content, line: offset + section.ContentLine})
+	}
+	l.template(segments)
+
+	if !found {
+		l.add(offset+1, Error, "no role marker like # CLAI::USER found")
+	}
+}
+
+// segment is the content of a message and the line of the file it starts on
+type segment struct {
+	content string
+	line    int
+}
+
+// template parses the contents of the messages as one template like the executor,
+// so actions can span messages, and checks the helper calls
+func (l *linter) template(segments []segment) {
+	if len(segments) == 0 {
+		return
+	}
+
+	// Each content is preceded by a separator line like in the executor
+	var document strings.Builder
+	starts := make([]int, len(segments))
+	line := 1
+	for i, seg := range segments {
+		document.WriteString("\n")
+		line++
+		starts[i] = line
+		document.WriteString(seg.content + "\n")
+		line += strings.Count(seg.content, "\n") + 1
+	}
+
+	// fileLine maps a line of the joined template to the line of the file
+	fileLine := func(line int) int {
+		i := max(sort.SearchInts(starts, line+1)-1, 0)
+		return segments[i].line + min(line-starts[i], strings.Count(segments[i].content, "\n"))
+	}
+
+	content := document.String()
+	tmpl, err := template.New("workflow").Parse(content)
+	if err != nil {
+		line, message := 1, err.Error()
+		if match := templateError.FindStringSubmatch(err.Error()); match != nil {
+			fmt.Sscanf(match[1], "%d", &line)
+			message = match[2]
+		}
+		// Unclosed actions are reported where they start
+		if match := templateStart.FindStringSubmatch(message); match != nil {
+			fmt.Sscanf(match[2], "%d", &line)
+			message = match[1]
+		}
+		l.add(fileLine(line), Error, "template: %s", message)
+		return
+	}
+
+	l.lineOf = func(pos parse.Pos) int {
+		return fileLine(1 + strings.Count(content[:int(pos)], "\n"))
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && t.Tree.Root != nil {
+			l.walk(t.Tree.Root)
+		}
+	}
+}
+
+// walk checks the helper calls of the node and its children
+func (l *linter) walk(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			l.walk(child)
+		}
+	case *parse.ActionNode:
+		l.walk(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			l.walk(cmd)
+		}
+	case *parse.CommandNode:
+		l.call(n)
+		for _, arg := range n.Args {
+			l.walk(arg)
+		}
+	case *parse.IfNode:
+		l.branch(&n.BranchNode)
+	case *parse.RangeNode:
+		l.branch(&n.BranchNode)
+	case *parse.WithNode:
+		l.branch(&n.BranchNode)
+	case *parse.TemplateNode:
+		l.walk(n.Pipe)
+	}
+}
+
+func (l *linter) branch(n *parse.BranchNode) {
+	l.walk(n.Pipe)
+	l.walk(n.List)
+	if n.ElseList != nil {
+		l.walk(n.ElseList)
+	}
+}
+
+// call checks the argument count and the literal arguments of "call .Helper ..."
+func (l *linter) call(n *parse.CommandNode) {
+	if len(n.Args) < 2 {
+		return
+	}
+	if ident, ok := n.Args[0].(*parse.IdentifierNode); !ok || ident.Ident != "call" {
+		return
+	}
+	field, ok := n.Args[1].(*parse.FieldNode)
+	if !ok || len(field.Ident) != 1 {
+		return
+	}
+
+	name := field.Ident[0]
+	signature, ok := l.helpers[name]
+	if !ok {
+		return
+	}
+
+	line := l.lineOf(n.Position())
+	args := n.Args[2:]
+
+	required := signature.NumIn()
+	if signature.IsVariadic() {
+		required--
+	}
+	if len(args) < required || (!signature.IsVariadic() && len(args) > required) {
+		expected := fmt.Sprint(required)
+		if signature.IsVariadic() {
+			expected = fmt.Sprintf("at least %d", required)
+		}
+		l.add(line, Error, "%s expects %s arguments, got %d", name, expected, len(args))
+		return
+	}
+
+	for i, arg := range args {
+		param := signature.In(min(i, signature.NumIn()-1))
+		if signature.IsVariadic() && i >= required {
+			param = param.Elem()
+		}
+		expected := kindName(param.Kind())
+		kind := literalKind(arg)
+		if kind == "" || expected == "" || kind == expected || (kind == "int" && expected == "float") {
+			continue
+		}
+		l.add(line, Error, "argument %d of %s must be a %s, got a %s", i+1, name, expected, kind)
+	}
+
+	l.path(name, args[0], line)
+}
+
+// attachments checks that the attached files exist
+func (l *linter) attachments(files []string, line int) {
+	for _, file := range files {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(l.workingDir, path)
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			l.add(line, Error, "attachment %s doesn't exist", file)
+		}
+	}
+}
+
+// path checks that the file or folder a helper reads exists
+func (l *linter) path(name string, arg parse.Node, line int) {
+	kind := executor.HelperPath(name)
+	str, ok := arg.(*parse.StringNode)
+	if kind == "" || !ok {
+		return
+	}
+
+	file := str.Text
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(l.workingDir, file)
+	}
+
+	info, err := os.Stat(file)
+	switch {
+	case err != nil:
+		l.add(line, Error, "%s %s of %s doesn't exist", kind, str.Text, name)
+	case kind == "folder" && !info.IsDir():
+		l.add(line, Error, "%s of %s is not a folder", str.Text, name)
+	case kind == "file" && info.IsDir():
+		l.add(line, Error, "%s of %s is a folder, expected a file", str.Text, name)
+	}
+}
+
+// literalKind returns the kind of a literal argument, or an empty string for other
+// arguments like fields or pipelines
+func literalKind(node parse.Node) string {
+	switch n := node.(type) {
+	case *parse.StringNode:
+		return "string"
+	case *parse.BoolNode:
+		return "bool"
+	case *parse.NumberNode:
+		if n.IsInt {
+			return "int"
+		}
+		return "float"
+	}
+	return ""
+}
+
+// kindName returns the kind of a parameter like literalKind, or an empty string if
+// it accepts any value
+func kindName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return "int"
+	case reflect.Float64, reflect.Float32:
+		return "float"
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "names.txt"), []byte("a\nb"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "monsters"), 0755))
+
+	tests := []struct {
+		name     string
+		content  string
+		expected []Issue
+	}{
+		{
+			name:    "valid",
+			content: "---\ndescription: ok\n---\n# CLAI::SYSTEM\nYou are helpful.\n# CLAI::USER\n{{ call .SampleLines \"names.txt\" 2 }}\n{{ if .Input }}{{ call .SampleFiles \"monsters\" 1 false }}{{ end }}\n{{ call .Image \"names.txt\" 512 }} {{ call .Glob \"*.txt\" }}",
+		},
+		{
+			name:    "unknown role",
+			content: "# CLAI::USR\nHi",
+			expected: []Issue{
+				{Line: 1, Severity: Error, Message: `unknown role "USR", expected SYSTEM, USER or ASSISTANT`},
+			},
+		},
+		{
+			name:    "no marker",
+			content: "Hi",
+			expected: []Issue{
+				{Line: 1, Severity: Warning, Message: "content before the first role marker is ignored"},
+				{Line: 1, Severity: Error, Message: "no role marker like # CLAI::USER found"},
+			},
+		},
+		{
+			name:    "empty message and leading content",
+			content: "---\nprofile: x\n---\nnotes\n# CLAI::SYSTEM\n\n# CLAI::USER\nHi",
+			expected: []Issue{
+				{Line: 4, Severity: Warning, Message: "content before the first role marker is ignored"},
+				{Line: 5, Severity: Warning, Message: "empty system message is dropped"},
+			},
+		},
+		{
+			name:    "unclosed action",
+			content: "---\nprofile: x\n---\n# CLAI::SYSTEM\nHi\n# CLAI::USER\nline\n{{ .Input",
+			expected: []Issue{
+				{Line: 8, Severity: Error, Message: "template: unclosed action"},
+			},
+		},
+		{
+			name:    "missing end",
+			content: "# CLAI::USER\n{{ if .Input }}\nHi",
+			expected: []Issue{
+				{Line: 3, Severity: Error, Message: "template: unexpected EOF"},
+			},
+		},
+		{
+			name:    "helper arity and types",
+			content: "# CLAI::USER\n{{ call .SampleLines \"names.txt\" }}\n{{ if true }}{{ call .SampleChunk \"names.txt\" \"10\" }}{{ end }}\n{{ call .SampleFilesPattern \"monsters\" \"*.md\" 1 false 2 }}",
+			expected: []Issue{
+				{Line: 2, Severity: Error, Message: "SampleLines expects 2 arguments, got 1"},
+				{Line: 3, Severity: Error, Message: "argument 2 of SampleChunk must be a int, got a string"},
+				{Line: 4, Severity: Error, Message: "SampleFilesPattern expects 4 arguments, got 5"},
+			},
+		},
+		{
+			name:    "paths",
+			content: "# CLAI::USER\n{{ call .File \"missing.txt\" }}\n{{ call .F \"monsters\" }}\n{{ call .SampleFiles \"names.txt\" 1 true }}\n{{ call .File .Input }}",
+			expected: []Issue{
+				{Line: 2, Severity: Error, Message: "file missing.txt of File doesn't exist"},
+				{Line: 3, Severity: Error, Message: "monsters of F is a folder, expected a file"},
+				{Line: 4, Severity: Error, Message: "names.txt of SampleFiles is not a folder"},
+			},
+		},
+		{
+			name:    "fenced and escaped markers",
+			content: "# CLAI::SYSTEM\n```md\n# CLAI::USR\n{{ .Input }}\n```\n\\# CLAI::USR\n# CLAI::USER name=dm\nHi",
+		},
+		{
+			name:    "actions spanning messages",
+			content: "# CLAI::SYSTEM\nHi\n{{ range .examples }}\n# CLAI::USER\n{{ .in }}\n# CLAI::ASSISTANT\n{{ .out }}\n{{ end }}\n# CLAI::USER\n{{ call .File \"missing.txt\" }}",
+			expected: []Issue{
+				{Line: 10, Severity: Error, Message: "file missing.txt of File doesn't exist"},
+			},
+		},
+		{
+			name:    "unclosed range spanning messages",
+			content: "# CLAI::SYSTEM\nHi\n{{ range .examples }}\n# CLAI::USER\n{{ .in }}",
+			expected: []Issue{
+				{Line: 5, Severity: Error, Message: "template: unexpected EOF"},
+			},
+		},
+		{
+			name:    "invalid attributes",
+			content: "# CLAI::USER cache=maybe\nHi\n# CLAI::USER colour=red\nHi",
+			expected: []Issue{
+				{Line: 1, Severity: Error, Message: `invalid value "maybe" of attribute cache`},
+				{Line: 3, Severity: Error, Message: `unknown attribute "colour"`},
+			},
+		},
+		{
+			name:    "invalid frontmatter",
+			content: "---\ninputs: [\n---\n# CLAI::USER\nHi",
+			expected: []Issue{
+				{Line: 2, Severity: Error, Message: "invalid frontmatter: yaml: line 1: did not find expected node content"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Lint(tt.content, dir))
+		})
+	}
+}
+
+func TestLintStructured(t *testing.T) {
+	dir := t.TempDir()
+
+	issues := LintStructured(`messages:
+  - role: usr
+    content: Hi
+  - role: user
+    content: |
+      Hi
+      {{ call .File "missing.txt" }}
+    attachments: [map.png]
+  - role: assistant
+    content: ""
+`, dir)
+	assert.Equal(t, []Issue{
+		{Line: 3, Severity: Error, Message: `unknown role "usr", expected system, user or assistant`},
+		{Line: 6, Severity: Error, Message: "attachment map.png doesn't exist"},
+		{Line: 7, Severity: Error, Message: "file missing.txt of File doesn't exist"},
+		{Line: 10, Severity: Warning, Message: "empty assistant message is dropped"},
+	}, issues)
+
+	issues = LintStructured("messages: [", dir)
+	require.Len(t, issues, 1)
+	assert.Equal(t, 1, issues[0].Line)
+}
@@ -0,0 +1,189 @@
+package postprocess
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Config describes how a response is processed. The steps are applied in the order
+// extract, regex, trim and template.
+type Config struct {
+	// Extract selects a part of the response: "codeblock", "codeblock:<lang>", "json"
+	// or "section:<heading>"
+	Extract string `yaml:"extract,omitempty" json:"extract,omitempty"`
+	// Regex selects the first match, or its first capture group if it has one
+	Regex string `yaml:"regex,omitempty" json:"regex,omitempty"`
+	// Trim removes leading and trailing whitespace
+	Trim bool `yaml:"trim,omitempty" json:"trim,omitempty"`
+	// Template reformats the result. It can use .Output, .Raw and .JSON (the parsed
+	// output if it is valid json)
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
+	// SaveRaw stores the unprocessed response next to the output file
+	SaveRaw bool `yaml:"save_raw,omitempty" json:"save_raw,omitempty"`
+}
+
+// IsZero reports if no processing is configured
+func (c Config) IsZero() bool {
+	return c.Extract == "" && c.Regex == "" && !c.Trim && c.Template == ""
+}
+
+// Apply processes the response according to the config
+func Apply(cfg Config, response string) (string, error) {
+	output := response
+
+	if cfg.Extract != "" {
+		kind, arg, _ := strings.Cut(cfg.Extract, ":")
+
+		var err error
+		switch kind {
+		case "codeblock":
+			output, err = ExtractCodeBlock(output, arg)
+		case "json":
+			output, err = ExtractJSON(output)
+		case "section":
+			output, err = ExtractSection(output, arg)
+		default:
+			err = fmt.Errorf("unknown extract mode %q", cfg.Extract)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.Regex != "" {
+		re, err := regexp.Compile(cfg.Regex)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex: %w", err)
+		}
+
+		match := re.FindStringSubmatch(output)
+		if match == nil {
+			return "", fmt.Errorf("regex %q didn't match the response", cfg.Regex)
+		}
+		if len(match) > 1 {
+			output = match[1]
+		} else {
+			output = match[0]
+		}
+	}
+
+	if cfg.Trim {
+		output = strings.TrimSpace(output)
+	}
+
+	if cfg.Template != "" {
+		tmpl, err := template.New("postprocess").Parse(cfg.Template)
+		if err != nil {
+			return "", fmt.Errorf("invalid template: %w", err)
+		}
+
+		data := map[string]any{
+			"Output": output,
+			"Raw":    response,
+		}
+		var parsed any
+		if json.Unmarshal([]byte(output), &parsed) == nil {
+			data["JSON"] = parsed
+		}
+
+		buf := &bytes.Buffer{}
+		if err := tmpl.Execute(buf, data); err != nil {
+			return "", fmt.Errorf("error executing template: %w", err)
+		}
+		output = buf.String()
+	}
+
+	return output, nil
+}
+
+// ExtractCodeBlock returns the content of the first fenced code block. If lang is set
+// the first block with that language is used.
+func ExtractCodeBlock(text string, lang string) (string, error) {
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "```") && !strings.HasPrefix(line, "~~~") {
+			continue
+		}
+
+		fence := line[:3]
+		info := strings.Fields(strings.TrimLeft(line, fence[:1]))
+
+		// Find the closing fence
+		end := i + 1
+		for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), fence) {
+			end++
+		}
+
+		if lang == "" || (len(info) > 0 && strings.EqualFold(info[0], lang)) {
+			if end >= len(lines) {
+				return "", errors.New("unclosed code block in response")
+			}
+			return strings.Join(lines[i+1:end], "\n"), nil
+		}
+
+		i = end
+	}
+
+	if lang != "" {
+		return "", fmt.Errorf("no %s code block found in response", lang)
+	}
+	return "", errors.New("no code block found in response")
+}
+
+// ExtractJSON returns the first valid json object or array in the text
+func ExtractJSON(text string) (string, error) {
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' && text[i] != '[' {
+			continue
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(text[i:]))
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			continue
+		}
+		return string(value), nil
+	}
+	return "", errors.New("no json found in response")
+}
+
+// ExtractSection returns the content below the markdown heading up to the next heading
+// of the same or a higher level
+func ExtractSection(text string, heading string) (string, error) {
+	heading = strings.TrimSpace(heading)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		level, title := parseHeading(line)
+		if level == 0 || !strings.EqualFold(title, heading) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) {
+			if nextLevel, _ := parseHeading(lines[end]); nextLevel > 0 && nextLevel <= level {
+				break
+			}
+			end++
+		}
+		return strings.TrimSpace(strings.Join(lines[i+1:end], "\n")), nil
+	}
+	return "", fmt.Errorf("section %q not found in response", heading)
+}
+
+// parseHeading returns the level and title of a markdown heading or 0 if the line
+// isn't one
+func parseHeading(line string) (int, string) {
+	trimmed := strings.TrimLeft(line, "#")
+	level := len(line) - len(trimmed)
+	if level == 0 || level > 6 || (trimmed != "" && trimmed[0] != ' ') {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(trimmed)
+}
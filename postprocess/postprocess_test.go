@@ -0,0 +1,44 @@
+package postprocess
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const response = "Here is your monster:\n\n```yaml\nname: Ghoul\ncr: 1\n```\n\n## Lore\nGhouls hunger.\n\n### Habitat\nCrypts.\n\n## Tactics\nSwarm.\n\nAs JSON: {\"name\": \"Ghoul\", \"tags\": [\"undead\"]} done."
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    string
+		wantErr bool
+	}{
+		{name: "no processing", cfg: Config{}, want: response},
+		{name: "codeblock", cfg: Config{Extract: "codeblock"}, want: "name: Ghoul\ncr: 1"},
+		{name: "codeblock with language", cfg: Config{Extract: "codeblock:YAML"}, want: "name: Ghoul\ncr: 1"},
+		{name: "missing codeblock language", cfg: Config{Extract: "codeblock:json"}, wantErr: true},
+		{name: "json", cfg: Config{Extract: "json"}, want: `{"name": "Ghoul", "tags": ["undead"]}`},
+		{name: "section", cfg: Config{Extract: "section:lore"}, want: "Ghouls hunger.\n\n### Habitat\nCrypts."},
+		{name: "missing section", cfg: Config{Extract: "section:Loot"}, wantErr: true},
+		{name: "unknown extract", cfg: Config{Extract: "xml"}, wantErr: true},
+		{name: "regex capture group", cfg: Config{Regex: `cr: (\d+)`}, want: "1"},
+		{name: "regex without match", cfg: Config{Regex: `hp: \d+`}, wantErr: true},
+		{name: "trim", cfg: Config{Extract: "section:Tactics", Regex: `(?s)^.*?\n\n`, Trim: true}, want: "Swarm."},
+		{name: "template with json", cfg: Config{Extract: "json", Template: "# {{ .JSON.name }} ({{ index .JSON.tags 0 }})"}, want: "# Ghoul (undead)"},
+		{name: "template with output", cfg: Config{Extract: "codeblock", Template: "---\n{{ .Output }}\n---"}, want: "---\nname: Ghoul\ncr: 1\n---"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(tt.cfg, response)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/bigjk/clai/ai"
+)
+
+// Compare sends the same rendered messages to every client concurrently. The results
+// are in the order of the clients, failed requests have Error set.
+func (r *Runner) Compare(input string, seed int64, messages []ai.Message, clients []*ai.Client) []*Result {
+	results := make([]*Result, len(clients))
+	wg := &sync.WaitGroup{}
+	wg.Add(len(clients))
+
+	for i, client := range clients {
+		go func(i int, client *ai.Client) {
+			defer wg.Done()
+
+			results[i] = &Result{Index: i + 1, Seed: seed, Input: input, Messages: messages}
+			sender := &Runner{Workflow: r.Workflow, Client: client, WorkingDir: r.WorkingDir, Options: r.Options}
+			if err := sender.Send(results[i]); err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, client)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// WriteCompareMarkdown writes a markdown report with a table of the latency and token
+// usage of every model followed by their outputs
+func WriteCompareMarkdown(w io.Writer, results []*Result) error {
+	var out strings.Builder
+	out.WriteString("# Model Comparison\n\n")
+	out.WriteString("| Model | Latency | Prompt Tokens | Completion Tokens | Total Tokens | Status |\n")
+	out.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	for _, result := range results {
+		status := "ok"
+		if result.Error != "" {
+			status = "error"
+		}
+		out.WriteString(fmt.Sprintf("| %s | %d ms | %d | %d | %d | %s |\n", result.Model, result.DurationMS, result.Usage.PromptTokens, result.Usage.CompletionTokens, result.Usage.TotalTokens, status))
+	}
+
+	for _, result := range results {
+		out.WriteString(fmt.Sprintf("\n## %s\n\n", result.Model))
+		if result.Error != "" {
+			out.WriteString(fmt.Sprintf("> Error: %s\n", strings.ReplaceAll(result.Error, "\n", " ")))
+			continue
+		}
+		out.WriteString(strings.TrimSpace(result.Response))
+		out.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+var compareHTML = template.Must(template.New("compare").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Model Comparison</title>
+<style>
+body { font-family: sans-serif; margin: 1.5rem; }
+.grid { display: grid; grid-template-columns: repeat({{ len . }}, minmax(20rem, 1fr)); gap: 1rem; }
+.column { border: 1px solid #ccc; border-radius: 4px; padding: 0.75rem; overflow-x: auto; }
+.stats { color: #555; font-size: 0.9rem; margin-bottom: 0.5rem; }
+.error { color: #b00020; }
+pre { white-space: pre-wrap; word-wrap: break-word; margin: 0; }
+</style>
+</head>
+<body>
+<h1>Model Comparison</h1>
+<div class="grid">
+{{- range . }}
+<div class="column">
+<h2>{{ .Model }}</h2>
+<div class="stats">{{ .DurationMS }} ms &middot; {{ .Usage.PromptTokens }} prompt + {{ .Usage.CompletionTokens }} completion = {{ .Usage.TotalTokens }} tokens</div>
+{{- if .Error }}
+<pre class="error">{{ .Error }}</pre>
+{{- else }}
+<pre>{{ .Response }}</pre>
+{{- end }}
+</div>
+{{- end }}
+</div>
+</body>
+</html>
+`))
+
+// WriteCompareHTML writes a html report showing the outputs of the models side by side
+func WriteCompareHTML(w io.Writer, results []*Result) error {
+	return compareHTML.Execute(w, results)
+}
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ai.Request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if req.Model == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("model is down"))
+			return
+		}
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":"<b>%s</b> says %s"}}],"usage":{"prompt_tokens":4,"completion_tokens":2,"total_tokens":6}}`, req.Model, req.Messages[0].Content)
+	}))
+	defer server.Close()
+
+	pool, err := ai.NewPool(ai.BalanceFailover,
+		&ai.Endpoint{Name: "primary", Client: ai.NewClient(ai.WithURL(server.URL), ai.WithModel("broken"))},
+		&ai.Endpoint{Name: "backup", Client: ai.NewClient(ai.WithURL(server.URL), ai.WithModel("backup"))},
+	)
+	require.NoError(t, err)
+
+	// The profile and the endpoint of the pool that answered are recorded
+	client := ai.NewClient(ai.WithPool(pool), ai.WithModel("primary"))
+	client.Profile = "batch"
+	result := &Result{Messages: []ai.Message{{Role: "user", Content: "hi"}}}
+	require.NoError(t, (&Runner{Client: client}).Send(result))
+	assert.Equal(t, "batch", result.Profile)
+	assert.Equal(t, "backup", result.Endpoint)
+	assert.Equal(t, "backup", result.Model)
+	assert.Equal(t, server.URL, result.URL)
+
+	var clients []*ai.Client
+	for _, model := range []string{"small", "broken", "large"} {
+		clients = append(clients, ai.NewClient(ai.WithURL(server.URL), ai.WithModel(model)))
+	}
+
+	messages := []ai.Message{{Role: "user", Content: "hi"}}
+	results := (&Runner{}).Compare("hi", 7, messages, clients)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "<b>small</b> says hi", results[0].Response)
+	assert.Equal(t, 6, results[0].Usage.TotalTokens)
+	assert.Equal(t, int64(7), results[0].Seed)
+	assert.Contains(t, results[1].Error, "model is down")
+	assert.Equal(t, "large", results[2].Model)
+	assert.Equal(t, messages, results[2].Messages)
+
+	var md bytes.Buffer
+	require.NoError(t, WriteCompareMarkdown(&md, results))
+	assert.Contains(t, md.String(), "| small | ")
+	assert.Regexp(t, `\| broken \| \d+ ms \| 0 \| 0 \| 0 \| error \|`, md.String())
+	assert.Contains(t, md.String(), "## large\n\n<b>large</b> says hi\n")
+
+	var html bytes.Buffer
+	require.NoError(t, WriteCompareHTML(&html, results))
+	assert.Contains(t, html.String(), "repeat(3, ")
+	assert.Contains(t, html.String(), "&lt;b&gt;small&lt;/b&gt; says hi")
+}
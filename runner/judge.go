@@ -0,0 +1,203 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bigjk/clai/executor"
+	"github.com/bigjk/clai/postprocess"
+)
+
+var (
+	scoreLineRegex = regexp.MustCompile(`(?im)^\W*(?:candidate\s*)?#?(\d+)\W*?[:=]\s*\**\s*(-?\d+(?:\.\d+)?)`)
+	rankingRegex   = regexp.MustCompile(`(?im)^\W*ranking\W*?:(.*)$`)
+	numberRegex    = regexp.MustCompile(`\d+`)
+)
+
+// Candidate is a response the judge workflow compares. In templates it prints as its
+// content, the index is the number of the run that produced it.
+type Candidate struct {
+	Index   int
+	Seed    int64
+	Content string
+}
+
+func (c Candidate) String() string {
+	return c.Content
+}
+
+// Score is the score the judge gave a candidate
+type Score struct {
+	Index int     `json:"index"`
+	Seed  int64   `json:"seed"`
+	Score float64 `json:"score"`
+	File  string  `json:"file,omitempty"`
+}
+
+// Verdict is the parsed result of the judge workflow
+type Verdict struct {
+	Winner int     `json:"winner"`
+	Scores []Score `json:"scores"`
+	Judge  *Result `json:"judge"`
+}
+
+// Judge runs the judge workflow of the runner with the candidates available as
+// .Candidates and picks the candidate with the highest score as winner.
+func (r *Runner) Judge(input string, seed int64, candidates []Candidate) (*Verdict, error) {
+	if len(candidates) == 0 {
+		return nil, errors.New("no candidates to judge")
+	}
+
+	messages, err := r.Render(input, seed, executor.WithData(map[string]any{"Candidates": candidates}))
+	if err != nil {
+		return nil, fmt.Errorf("judge: %w", err)
+	}
+
+	result := &Result{Index: 1, Seed: seed, Input: input, Messages: messages}
+	verdict := &Verdict{Judge: result}
+	if err := r.Send(result); err != nil {
+		return verdict, fmt.Errorf("judge: %w", err)
+	}
+
+	indexes := make([]int, len(candidates))
+	for i, candidate := range candidates {
+		indexes[i] = candidate.Index
+	}
+
+	scores, err := ParseScores(result.Response, indexes)
+	if err != nil {
+		return verdict, fmt.Errorf("judge: %w", err)
+	}
+
+	best := -1.0
+	for _, candidate := range candidates {
+		score, ok := scores[candidate.Index]
+		if !ok {
+			continue
+		}
+
+		verdict.Scores = append(verdict.Scores, Score{Index: candidate.Index, Seed: candidate.Seed, Score: score})
+		if verdict.Winner == 0 || score > best {
+			verdict.Winner = candidate.Index
+			best = score
+		}
+	}
+
+	return verdict, nil
+}
+
+// ParseScores parses the scores of the candidates with the given indexes from a judge
+// response. Supported are json like {"scores": {"1": 8, "2": 5}}, [{"candidate": 1,
+// "score": 8}] or {"ranking": [2, 1]}, lines like "Candidate 1: 8/10" and a line like
+// "Ranking: 2, 1, 3". Rankings are turned into scores, the first place scores highest.
+func ParseScores(text string, indexes []int) (map[int]float64, error) {
+	valid := map[int]bool{}
+	for _, index := range indexes {
+		valid[index] = true
+	}
+
+	scores := map[int]float64{}
+	add := func(index int, score float64) {
+		if valid[index] {
+			if _, ok := scores[index]; !ok {
+				scores[index] = score
+			}
+		}
+	}
+	addRanking := func(ranking []int) {
+		for place, index := range ranking {
+			add(index, float64(len(ranking)-place))
+		}
+	}
+
+	if raw, err := postprocess.ExtractJSON(text); err == nil {
+		var data any
+		if json.Unmarshal([]byte(raw), &data) == nil {
+			parseJSONScores(data, add, addRanking)
+		}
+	}
+
+	if len(scores) == 0 {
+		for _, match := range scoreLineRegex.FindAllStringSubmatch(text, -1) {
+			index, _ := strconv.Atoi(match[1])
+			score, _ := strconv.ParseFloat(match[2], 64)
+			add(index, score)
+		}
+	}
+
+	if len(scores) == 0 {
+		if match := rankingRegex.FindStringSubmatch(text); match != nil {
+			var ranking []int
+			for _, number := range numberRegex.FindAllString(match[1], -1) {
+				index, _ := strconv.Atoi(number)
+				ranking = append(ranking, index)
+			}
+			addRanking(ranking)
+		}
+	}
+
+	if len(scores) == 0 {
+		return nil, errors.New("no scores found in judge response")
+	}
+	return scores, nil
+}
+
+func parseJSONScores(data any, add func(int, float64), addRanking func([]int)) {
+	switch value := data.(type) {
+	case map[string]any:
+		for _, key := range []string{"scores", "results", "candidates"} {
+			if nested, ok := value[key]; ok {
+				parseJSONScores(nested, add, addRanking)
+				return
+			}
+		}
+		if ranking, ok := value["ranking"].([]any); ok {
+			addRanking(jsonInts(ranking))
+			return
+		}
+		for key, score := range value {
+			index, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(key), "candidate"))
+			if number, ok := score.(float64); ok && err == nil {
+				add(index, number)
+			}
+		}
+	case []any:
+		for _, item := range value {
+			entry, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			score, ok := entry["score"].(float64)
+			if !ok {
+				continue
+			}
+			for _, key := range []string{"index", "candidate", "id"} {
+				if index, ok := entry[key].(float64); ok {
+					add(int(index), score)
+					break
+				}
+			}
+		}
+	}
+}
+
+// jsonInts converts the numbers of a json list to ints
+func jsonInts(list []any) []int {
+	var ints []int
+	for _, item := range list {
+		switch value := item.(type) {
+		case float64:
+			ints = append(ints, int(value))
+		case string:
+			if index, err := strconv.Atoi(value); err == nil {
+				ints = append(ints, index)
+			}
+		}
+	}
+	return ints
+}
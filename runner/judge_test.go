@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/templating"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScores(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    map[int]float64
+		wantErr bool
+	}{
+		{name: "json scores", text: "Here you go:\n```json\n{\"scores\": {\"1\": 8, \"3\": 5.5}}\n```", want: map[int]float64{1: 8, 3: 5.5}},
+		{name: "json list", text: `[{"candidate": 1, "score": 2}, {"candidate": 3, "score": 9}]`, want: map[int]float64{1: 2, 3: 9}},
+		{name: "json ranking", text: `{"ranking": [3, 1]}`, want: map[int]float64{3: 2, 1: 1}},
+		{name: "json candidate keys", text: `{"candidate1": 4, "candidate3": 7}`, want: map[int]float64{1: 4, 3: 7}},
+		{name: "lines", text: "Candidate 1: 7/10, solid\n**Candidate 3**: 9\n- 2: 10", want: map[int]float64{1: 7, 3: 9}},
+		{name: "ranking line", text: "After careful thought.\nRanking: 3 > 1", want: map[int]float64{3: 2, 1: 1}},
+		{name: "unknown candidates", text: `{"scores": {"5": 10}}`, wantErr: true},
+		{name: "no scores", text: "They are all great!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseScores(tt.text, []int{1, 3})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestJudge(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.yaml")
+	require.NoError(t, os.WriteFile(script, []byte(`
+- match: "Candidate 2: Ghoul"
+  content: '{"scores": {"1": 4, "2": 9, "3": 9}}'
+`), 0644))
+
+	workflow, err := templating.ParseWorkflow(`# CLAI::USER
+Pick the best {{ .Input }}:
+{{ range .Candidates }}
+Candidate {{ .Index }}: {{ . }}
+{{ end }}`)
+	require.NoError(t, err)
+
+	judge := &Runner{Workflow: workflow, Client: ai.NewClient(ai.WithMock(script)), WorkingDir: t.TempDir()}
+	verdict, err := judge.Judge("monster", 1, []Candidate{
+		{Index: 1, Content: "Goblin"},
+		{Index: 2, Content: "Ghoul"},
+		{Index: 3, Content: "Lich"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, verdict.Winner)
+	assert.Equal(t, []Score{{Index: 1, Score: 4}, {Index: 2, Score: 9}, {Index: 3, Score: 9}}, verdict.Scores)
+	assert.Contains(t, verdict.Judge.Messages[0].Content, "Candidate 3: Lich")
+
+	_, err = judge.Judge("monster", 1, []Candidate{{Index: 1, Content: "Goblin"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,288 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+)
+
+// Output formats
+const (
+	FormatMarkdown = "md"
+	FormatJSON     = "json"
+	FormatJSONL    = "jsonl"
+)
+
+// Collision policies for output files that already exist
+const (
+	CollisionOverwrite = "overwrite"
+	CollisionSkip      = "skip"
+	CollisionSuffix    = "suffix"
+)
+
+var headingRegex = regexp.MustCompile(`(?m)^#{1,6}\s+(.+?)\s*#*\s*$`)
+
+// Output writes the results of multiple runs into a directory. The file name of
+// each result is derived from a text/template pattern.
+type Output struct {
+	Dir       string
+	Pattern   string
+	Format    string
+	Collision string
+
+	tmpl    *template.Template
+	mu      sync.Mutex
+	claimed map[string]bool
+}
+
+// PatternData are the variables available in the output file pattern
+type PatternData struct {
+	Index     int
+	Seed      int64
+	Timestamp string
+	Fields    map[string]any
+	Slug      string
+	Ext       string
+}
+
+// NewOutput validates the options and creates an output. If the pattern is empty a
+// default for the format is used.
+func NewOutput(dir, pattern, format, collision string) (*Output, error) {
+	switch format {
+	case "":
+		format = FormatMarkdown
+	case FormatMarkdown, FormatJSON, FormatJSONL:
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected md, json or jsonl", format)
+	}
+
+	switch collision {
+	case "":
+		collision = CollisionOverwrite
+	case CollisionOverwrite, CollisionSkip, CollisionSuffix:
+	default:
+		return nil, fmt.Errorf("unknown collision policy %q, expected overwrite, skip or suffix", collision)
+	}
+
+	if pattern == "" {
+		pattern = "res_{{ .Index }}.{{ .Ext }}"
+		if format == FormatJSONL {
+			pattern = "results.jsonl"
+		}
+	}
+
+	tmpl, err := template.New("out").Option("missingkey=zero").Parse(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing output pattern: %w", err)
+	}
+
+	return &Output{
+		Dir:       dir,
+		Pattern:   pattern,
+		Format:    format,
+		Collision: collision,
+		tmpl:      tmpl,
+		claimed:   map[string]bool{},
+	}, nil
+}
+
+// FileName renders the pattern for a result
+func (o *Output) FileName(result *Result) (string, error) {
+	data := PatternData{
+		Index:     result.Index,
+		Seed:      result.Seed,
+		Timestamp: result.StartedAt.Format("20060102-150405"),
+		Fields:    InputFields(result.Input),
+		Slug:      Slug(result.Response),
+		Ext:       o.Format,
+	}
+
+	var name bytes.Buffer
+	if err := o.tmpl.Execute(&name, data); err != nil {
+		return "", fmt.Errorf("error executing output pattern: %w", err)
+	}
+	if strings.TrimSpace(name.String()) == "" {
+		return "", fmt.Errorf("output pattern %q produced an empty file name", o.Pattern)
+	}
+
+	return filepath.Join(o.Dir, filepath.FromSlash(name.String())), nil
+}
+
+// Write writes a result. For the markdown format content is written, the json formats
+// write the whole result record. The written file is returned, or an empty string
+// if it was skipped because of the collision policy.
+func (o *Output) Write(result *Result, content string) (string, error) {
+	file, err := o.FileName(result)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", fmt.Errorf("error creating output directory: %w", err)
+	}
+
+	if o.Format == FormatJSONL {
+		return file, o.appendRecord(file, result)
+	}
+
+	file, ok := o.claim(file)
+	if !ok {
+		return "", nil
+	}
+
+	data, err := o.Encode(result, content)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return "", fmt.Errorf("error writing result file: %w", err)
+	}
+	return file, nil
+}
+
+// Encode returns the content of a result file in the format of the output. Markdown
+// files contain the content, json files the whole result and jsonl files the result as
+// a single line.
+func (o *Output) Encode(result *Result, content string) ([]byte, error) {
+	var data []byte
+	var err error
+	switch o.Format {
+	case FormatJSON:
+		data, err = json.MarshalIndent(result, "", "  ")
+	case FormatJSONL:
+		data, err = json.Marshal(result)
+		data = append(data, '\n')
+	default:
+		return []byte(content), nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error encoding result: %w", err)
+	}
+	return data, nil
+}
+
+// claim reserves a file name according to the collision policy. Files written by
+// this output count as existing too, so concurrent runs never share a file.
+func (o *Output) claim(file string) (string, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	exists := func(file string) bool {
+		if o.claimed[file] {
+			return true
+		}
+		_, err := os.Stat(file)
+		return err == nil
+	}
+
+	if exists(file) {
+		switch o.Collision {
+		case CollisionSkip:
+			return "", false
+		case CollisionSuffix:
+			ext := filepath.Ext(file)
+			base := strings.TrimSuffix(file, ext)
+			for i := 2; exists(file); i++ {
+				file = fmt.Sprintf("%s_%d%s", base, i, ext)
+			}
+		}
+	}
+
+	o.claimed[file] = true
+	return file, true
+}
+
+// appendRecord appends the result as a single json line to the file
+func (o *Output) appendRecord(file string, result *Result) error {
+	line, err := o.Encode(result, "")
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Within one invocation all records are appended, but an existing file from a
+	// previous invocation is handled by the collision policy.
+	if !o.claimed[file] {
+		if _, err := os.Stat(file); err == nil {
+			switch o.Collision {
+			case CollisionSkip:
+				return nil
+			case CollisionOverwrite:
+				if err := os.Remove(file); err != nil {
+					return fmt.Errorf("error removing result file: %w", err)
+				}
+			}
+		}
+		o.claimed[file] = true
+	}
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening result file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("error writing result file: %w", err)
+	}
+	return nil
+}
+
+// InputFields returns the fields of a json object input. Any other input is
+// available as the "Input" field, like in the workflow templates.
+func InputFields(input string) map[string]any {
+	fields := map[string]any{}
+	if err := json.Unmarshal([]byte(input), &fields); err != nil || fields == nil {
+		fields = map[string]any{"Input": input}
+	}
+	return fields
+}
+
+// Slug returns a file name friendly slug of the first markdown heading of the text,
+// or of the first non-empty line if there is no heading.
+func Slug(text string) string {
+	title := ""
+	if match := headingRegex.FindStringSubmatch(text); match != nil {
+		title = match[1]
+	} else {
+		for _, line := range strings.Split(text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				title = line
+				break
+			}
+		}
+	}
+
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			slug.WriteRune(r)
+			dash = false
+		case slug.Len() > 0 && !dash:
+			slug.WriteRune('-')
+			dash = true
+		}
+		if slug.Len() >= 60 {
+			break
+		}
+	}
+
+	result := strings.Trim(slug.String(), "-")
+	if result == "" {
+		return "untitled"
+	}
+	return result
+}
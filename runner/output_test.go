@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Intro\n\n## The Red Dragon's Lair ##\ntext", want: "the-red-dragon-s-lair"},
+		{text: "# Ghoul (CR 1)", want: "ghoul-cr-1"},
+		{text: "\n  Just a line!\nmore", want: "just-a-line"},
+		{text: "", want: "untitled"},
+		{text: "# ???", want: "untitled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, Slug(tt.text))
+		})
+	}
+}
+
+func TestOutputFileName(t *testing.T) {
+	out, err := NewOutput("out", `{{ .Fields.monster }}/{{ .Slug }}_{{ .Seed }}.{{ .Ext }}`, FormatJSON, "")
+	require.NoError(t, err)
+
+	file, err := out.FileName(&Result{Seed: 42, Input: `{"monster": "ghoul"}`, Response: "# Hungry Ghoul"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("out", "ghoul", "hungry-ghoul_42.json"), file)
+
+	_, err = NewOutput("out", "", "xml", "")
+	assert.Error(t, err)
+	_, err = NewOutput("out", "", "", "rename")
+	assert.Error(t, err)
+	_, err = NewOutput("out", "{{ .Index", "", "")
+	assert.Error(t, err)
+}
+
+func TestOutputCollision(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "res.md"), []byte("old"), 0644))
+
+	tests := []struct {
+		collision string
+		want      []string
+	}{
+		{collision: CollisionOverwrite, want: []string{"res.md", "res.md"}},
+		{collision: CollisionSkip, want: []string{"", ""}},
+		{collision: CollisionSuffix, want: []string{"res_2.md", "res_3.md"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.collision, func(t *testing.T) {
+			out, err := NewOutput(dir, "res.md", FormatMarkdown, tt.collision)
+			require.NoError(t, err)
+
+			for i, want := range tt.want {
+				file, err := out.Write(&Result{Index: i + 1}, "new")
+				require.NoError(t, err)
+				if want == "" {
+					assert.Empty(t, file)
+				} else {
+					assert.Equal(t, filepath.Join(dir, want), file)
+				}
+			}
+		})
+	}
+}
+
+func TestOutputJSONL(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "results.jsonl"), []byte("{}\n"), 0644))
+
+	out, err := NewOutput(dir, "", FormatJSONL, CollisionOverwrite)
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		_, err := out.Write(&Result{Index: i, Response: "response"}, "")
+		require.NoError(t, err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "results.jsonl"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	var indexes []int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var result Result
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+		indexes = append(indexes, result.Index)
+	}
+	assert.Equal(t, []int{1, 2, 3}, indexes)
+}
+
+func TestOutputEncode(t *testing.T) {
+	result := &Result{Index: 2, Response: "# Ghoul"}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{format: FormatMarkdown, expected: "# Ghoul"},
+		{format: FormatJSON, expected: "{\n  \"index\": 2,"},
+		{format: FormatJSONL, expected: "{\"index\":2,"},
+	}
+
+	for _, tt := range tests {
+		out, err := NewOutput(t.TempDir(), "", tt.format, CollisionOverwrite)
+		require.NoError(t, err)
+
+		data, err := out.Encode(result, result.Response)
+		require.NoError(t, err, tt.format)
+		assert.True(t, strings.HasPrefix(string(data), tt.expected), "%s: %s", tt.format, data)
+		if tt.format != FormatMarkdown {
+			var decoded Result
+			require.NoError(t, json.Unmarshal(data, &decoded), tt.format)
+			assert.Equal(t, "# Ghoul", decoded.Response, tt.format)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/executor"
+	"github.com/bigjk/clai/postprocess"
+	"github.com/bigjk/clai/templating"
+)
+
+// Runner renders a workflow and sends it to the api
+type Runner struct {
+	Workflow   *templating.Workflow
+	Client     *ai.Client
+	WorkingDir string
+	Options    []executor.Options
+}
+
+// Result is the outcome of a single run of a workflow
+type Result struct {
+	Index      int          `json:"index"`
+	Seed       int64        `json:"seed"`
+	Input      string       `json:"input"`
+	Profile    string       `json:"profile,omitempty"`
+	Model      string       `json:"model"`
+	URL        string       `json:"url"`
+	Endpoint   string       `json:"endpoint,omitempty"`
+	Messages   []ai.Message `json:"messages"`
+	Response   string       `json:"response"`
+	Raw        string       `json:"raw"`
+	Usage      ai.Usage     `json:"usage"`
+	StartedAt  time.Time    `json:"started_at"`
+	DurationMS int64        `json:"duration_ms"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// NewSeed returns a random seed
+func NewSeed() int64 {
+	return rand.Int63()
+}
+
+// Render executes the templates of the workflow with the given input and seed. The
+// options are applied after the options of the runner.
+func (r *Runner) Render(input string, seed int64, opts ...executor.Options) ([]ai.Message, error) {
+	opts = append(append(append([]executor.Options{}, r.Options...), executor.WithSeed(seed)), opts...)
+
+	messages, err := executor.Execute(r.Workflow.Messages, input, r.WorkingDir, opts...)
+	if err != nil {
+		var msgErr *executor.MessageError
+		if errors.As(err, &msgErr) && msgErr.Index < len(r.Workflow.Lines) {
+			line := r.Workflow.Lines[msgErr.Index]
+			if msgErr.Line > 0 {
+				line += msgErr.Line - 1
+			}
+			return nil, fmt.Errorf("error executing command: line %d: %w", line, err)
+		}
+		return nil, fmt.Errorf("error executing command: %w", err)
+	}
+	return messages, nil
+}
+
+// Run renders the workflow and sends it to the api. If the post-processing fails
+// the result containing the raw response is returned together with the error.
+func (r *Runner) Run(input string, seed int64) (*Result, error) {
+	messages, err := r.Render(input, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Seed:     seed,
+		Input:    input,
+		Messages: messages,
+	}
+	return result, r.Send(result)
+}
+
+// Send sends the messages of the result to the api and stores the response in it
+func (r *Runner) Send(result *Result) error {
+	return r.Stream(result, nil)
+}
+
+// Stream is like Send, but calls onDelta with the parts of the unprocessed response
+// as they arrive. A nil onDelta doesn't stream.
+func (r *Runner) Stream(result *Result, onDelta func(delta string)) error {
+	result.Profile = r.Client.Profile
+	result.Model = r.Client.Model
+	result.URL = r.Client.URL
+	result.StartedAt = time.Now()
+
+	var completion *ai.Completion
+	var err error
+	if onDelta != nil {
+		completion, err = r.Client.Stream(result.Messages, onDelta)
+	} else {
+		completion, err = r.Client.Complete(result.Messages)
+	}
+	result.DurationMS = time.Since(result.StartedAt).Milliseconds()
+	if err != nil {
+		return fmt.Errorf("error getting response: %w", err)
+	}
+
+	result.Raw = completion.Content
+	result.Usage = completion.Usage
+
+	// Record the endpoint of the pool that answered
+	if endpoint := r.Client.Pool.Endpoint(completion.Endpoint); endpoint != nil {
+		result.Endpoint = endpoint.Name
+		result.Model = endpoint.Client.Model
+		result.URL = endpoint.Client.URL
+	}
+
+	var meta postprocess.Config
+	if r.Workflow != nil {
+		meta = r.Workflow.Meta.Config
+	}
+
+	result.Response, err = postprocess.Apply(meta, completion.Content)
+	if err != nil {
+		return fmt.Errorf("error processing response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("CLAI_TEST_KEY", "sk-123")
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "${CLAI_TEST_KEY}", expected: "sk-123"},
+		{value: "Bearer ${CLAI_TEST_KEY}!", expected: "Bearer sk-123!"},
+		{value: "${CLAI_TEST_UNSET}", expected: ""},
+		{value: "$CLAI_TEST_KEY", expected: "$CLAI_TEST_KEY"},
+		{value: "pa$$word", expected: "pa$$word"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Expand(tt.value), tt.value)
+	}
+}
+
+func TestCommandAndFile(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		key, err := Command("echo sk-456")
+		require.NoError(t, err)
+		assert.Equal(t, "sk-456", key)
+
+		_, err = Command("echo broken >&2; exit 3")
+		assert.ErrorContains(t, err, "broken")
+	}
+
+	file := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(file, []byte("  sk-789\n"), 0600))
+
+	key, err := ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-789", key)
+
+	_, err = ReadFile(file + ".missing")
+	assert.Error(t, err)
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	names, err := store.Names()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	require.NoError(t, store.Set("openai", "sk-secret-openai"))
+	require.NoError(t, store.Set("local", "sk-secret-local"))
+
+	data, err := os.ReadFile(filepath.Join(dir, storeFile))
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(data, []byte("sk-secret")), "keys are stored in plain text")
+
+	key, ok, err := NewStore(dir).Get("openai")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "sk-secret-openai", key)
+
+	require.NoError(t, store.Delete("openai"))
+	assert.Error(t, store.Delete("openai"))
+
+	names, err = store.Names()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"local"}, names)
+
+	// A different encryption key can't decrypt the store
+	require.NoError(t, os.WriteFile(filepath.Join(dir, keyFile), bytes.Repeat([]byte{1}, 32), 0600))
+	_, _, err = store.Get("local")
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	storeFile = "keys.enc"
+	keyFile   = "keys.key"
+)
+
+// Store keeps api keys encrypted with AES-GCM in a directory. The encryption key is a
+// random key in a separate file, so the keys never end up in plain text in a config
+// or a repository. It doesn't protect against someone with access to the user account.
+type Store struct {
+	Dir string
+}
+
+// DefaultDir returns the default directory of the store inside the user config directory
+func DefaultDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "clai")
+}
+
+// NewStore creates a store in the directory, or in the default directory if it is empty
+func NewStore(dir string) *Store {
+	if dir == "" {
+		dir = DefaultDir()
+	}
+	return &Store{Dir: dir}
+}
+
+// Get returns the key stored under the name
+func (s *Store) Get(name string) (string, bool, error) {
+	keys, err := s.load()
+	if err != nil {
+		return "", false, err
+	}
+	key, ok := keys[name]
+	return key, ok, nil
+}
+
+// Set stores the key under the name, replacing an existing key
+func (s *Store) Set(name string, key string) error {
+	keys, err := s.load()
+	if err != nil {
+		return err
+	}
+	keys[name] = key
+	return s.save(keys)
+}
+
+// Delete removes the key stored under the name
+func (s *Store) Delete(name string) error {
+	keys, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := keys[name]; !ok {
+		return fmt.Errorf("no key stored for %q", name)
+	}
+	delete(keys, name)
+	return s.save(keys)
+}
+
+// Names returns the sorted names of all stored keys
+func (s *Store) Names() ([]string, error) {
+	keys, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// gcm returns the cipher of the store, creating the encryption key if requested
+func (s *Store) gcm(create bool) (cipher.AEAD, error) {
+	file := filepath.Join(s.Dir, keyFile)
+	secret, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) && create {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("error generating encryption key: %w", err)
+		}
+		if err := os.MkdirAll(s.Dir, 0700); err != nil {
+			return nil, fmt.Errorf("error creating key store directory: %w", err)
+		}
+		if err := os.WriteFile(file, secret, 0600); err != nil {
+			return nil, fmt.Errorf("error writing encryption key: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading encryption key: %w", err)
+	}
+
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key %s: %w", file, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// load decrypts the stored keys. A missing store has no keys.
+func (s *Store) load() (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, storeFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading key store: %w", err)
+	}
+
+	gcm, err := s.gcm(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("key store is corrupted")
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting key store: %w", err)
+	}
+
+	keys := map[string]string{}
+	if err := json.Unmarshal(plain, &keys); err != nil {
+		return nil, fmt.Errorf("error decoding key store: %w", err)
+	}
+	return keys, nil
+}
+
+// save encrypts the keys with a fresh nonce
+func (s *Store) save(keys map[string]string) error {
+	gcm, err := s.gcm(true)
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("error encoding key store: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("error generating nonce: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(s.Dir, storeFile), gcm.Seal(nonce, nonce, plain, nil), 0600); err != nil {
+		return fmt.Errorf("error writing key store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/runner"
+)
+
+// ModelPrefix is the prefix of the model names that select a workflow in the proxy
+const ModelPrefix = "clai/"
+
+// ProxyHandler returns an OpenAI compatible http handler. Requests for the model
+// "clai/<name>" render the workflow with the last user message as input and send the
+// rendered messages upstream with the other fields of the request, requests for other
+// models are forwarded unchanged.
+//
+//	GET  /v1/models            lists the workflows as models
+//	POST /v1/chat/completions  creates a chat completion, optionally streamed
+func (s *Server) ProxyHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/models", s.handleModels)
+	mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	return s.authorize(mux)
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	names, err := s.workflowNames()
+	if err != nil {
+		writeProxyError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	models := []map[string]any{}
+	for _, name := range names {
+		models = append(models, map[string]any{
+			"id":       ModelPrefix + name,
+			"object":   "model",
+			"owned_by": "clai",
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": models})
+}
+
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 10<<20))
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, fmt.Errorf("error reading request: %w", err))
+		return
+	}
+
+	var req ai.Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeProxyError(w, http.StatusBadRequest, fmt.Errorf("error decoding request: %w", err))
+		return
+	}
+
+	name, ok := strings.CutPrefix(req.Model, ModelPrefix)
+	if !ok {
+		s.forward(w, r, body, req.Model)
+		return
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		writeProxyError(w, http.StatusNotFound, fmt.Errorf("unknown workflow %q", name))
+		return
+	}
+
+	messages, err := proxyMessages(req.Messages)
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, err)
+		return
+	}
+	params, err := proxyParams(body)
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	file := s.workflowFile(name)
+	workflow, data, err := loadWorkflow(file)
+	if err != nil {
+		writeProxyError(w, http.StatusNotFound, fmt.Errorf("unknown workflow %q", name))
+		return
+	}
+
+	client, err := s.client(workflow.Meta.Profile)
+	if err != nil {
+		writeProxyError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// The clients are shared, so the params of the request are set on a copy
+	upstream := *client
+	upstream.Params = params
+
+	// Rendering runs the helpers, so it counts against the concurrency limit as well
+	if !s.acquire(r) {
+		writeProxyError(w, http.StatusServiceUnavailable, r.Context().Err())
+		return
+	}
+	defer s.release()
+
+	rn := &runner.Runner{Workflow: workflow, Client: &upstream, WorkingDir: s.WorkingDir, Options: s.options(client)}
+	result := &runner.Result{Input: lastUserMessage(messages), Seed: runner.NewSeed()}
+	if result.Messages, err = rn.Render(result.Input, result.Seed); err != nil {
+		writeProxyError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id := completionID()
+	created := time.Now().Unix()
+
+	flusher, canFlush := w.(http.Flusher)
+	if !req.Stream || !canFlush {
+		err := rn.Send(result)
+		s.record(&request{file: file, content: data}, result, err)
+		if err != nil {
+			writeProxyError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": created,
+			"model":   req.Model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": result.Response},
+				"finish_reason": "stop",
+			}},
+			"usage": result.Usage,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	chunk := func(delta map[string]string, finish any) {
+		writeData(w, map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   req.Model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finish}},
+		})
+		flusher.Flush()
+	}
+
+	chunk(map[string]string{"role": "assistant"}, nil)
+	err = rn.Stream(result, func(delta string) {
+		chunk(map[string]string{"content": delta}, nil)
+	})
+	s.record(&request{file: file, content: data}, result, err)
+	if err != nil {
+		writeData(w, map[string]any{"error": map[string]string{"message": err.Error(), "type": "upstream_error"}})
+	} else {
+		chunk(map[string]string{}, "stop")
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// forward sends a request for another model unchanged to the api of the default
+// profile and copies the response back as it arrives
+func (s *Server) forward(w http.ResponseWriter, r *http.Request, body []byte, model string) {
+	client, err := s.client("")
+	if err != nil {
+		writeProxyError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	forward := *client
+	if model != "" {
+		if err := forward.SetModel(model); err != nil {
+			writeProxyError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if !s.acquire(r) {
+		writeProxyError(w, http.StatusServiceUnavailable, r.Context().Err())
+		return
+	}
+	defer s.release()
+
+	resp, err := forward.Forward(body)
+	if err != nil {
+		writeProxyError(w, http.StatusBadGateway, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, header := range []string{"Content-Type", "Cache-Control"} {
+		if value := resp.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	// Streamed responses are passed on with every read
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// proxyParams returns the fields of a request for a workflow that are sent along with
+// the rendered messages, like temperature or max_tokens
+func proxyParams(body []byte) (map[string]json.RawMessage, error) {
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(body, &params); err != nil {
+		return nil, fmt.Errorf("error decoding request: %w", err)
+	}
+
+	// The model selects the workflow and the messages are rendered. The stream
+	// options only apply to streamed requests, which clai decides about.
+	for _, key := range []string{"model", "messages", "stream", "stream_options"} {
+		delete(params, key)
+	}
+	return params, nil
+}
+
+// proxyMessages converts the messages of an OpenAI request to find the input of a
+// workflow. Only the text parts of the content are kept.
+func proxyMessages(messages []ai.RequestMessage) ([]ai.Message, error) {
+	converted := make([]ai.Message, 0, len(messages))
+	for _, msg := range messages {
+		switch content := msg.Content.(type) {
+		case string:
+			converted = append(converted, ai.Message{Role: msg.Role, Content: content})
+		case []any:
+			var text []string
+			for _, part := range content {
+				if part, ok := part.(map[string]any); ok && part["type"] == "text" {
+					text = append(text, fmt.Sprint(part["text"]))
+				}
+			}
+			converted = append(converted, ai.Message{Role: msg.Role, Content: strings.Join(text, "\n")})
+		case nil:
+			converted = append(converted, ai.Message{Role: msg.Role})
+		default:
+			return nil, fmt.Errorf("unsupported content of %s message", msg.Role)
+		}
+	}
+	return converted, nil
+}
+
+// lastUserMessage returns the content of the last user message
+func lastUserMessage(messages []ai.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+func completionID() string {
+	suffix := make([]byte, 12)
+	_, _ = rand.Read(suffix)
+	return "chatcmpl-" + hex.EncodeToString(suffix)
+}
+
+func writeProxyError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]any{"error": map[string]string{"message": err.Error(), "type": http.StatusText(status)}})
+}
+
+func writeData(w http.ResponseWriter, value any) {
+	data, _ := json.Marshal(value)
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
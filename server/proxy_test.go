@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ai.Request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		content := fmt.Sprintf("%s: %s", req.Model, req.Messages[len(req.Messages)-1].Content)
+		if !req.Stream {
+			data, _ := json.Marshal(content)
+			fmt.Fprintf(w, `{"choices":[{"message":{"content":%s}}],"usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3}}`, data)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, word := range strings.SplitAfter(content, " ") {
+			data, _ := json.Marshal(word)
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%s}}]}\n\n", data)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer upstream.Close()
+
+	s, _ := newTestServer(t, func(profile string) (*ai.Client, error) {
+		return ai.NewClient(ai.WithURL(upstream.URL), ai.WithModel("upstream")), nil
+	})
+	proxy := httptest.NewServer(s.ProxyHandler())
+	defer proxy.Close()
+
+	completion := func(body string) map[string]any {
+		resp, data := doRequest(t, "POST", proxy.URL+"/v1/chat/completions", body)
+		require.Equal(t, http.StatusOK, resp.StatusCode, data)
+
+		var res map[string]any
+		require.NoError(t, json.Unmarshal([]byte(data), &res))
+		return res
+	}
+	content := func(res map[string]any) string {
+		return res["choices"].([]any)[0].(map[string]any)["message"].(map[string]any)["content"].(string)
+	}
+
+	// The last user message is the input of the workflow
+	res := completion(`{"model": "clai/echo", "messages": [
+		{"role": "system", "content": "ignored"},
+		{"role": "user", "content": "first"},
+		{"role": "assistant", "content": "ok"},
+		{"role": "user", "content": [{"type": "text", "text": "second"}]}
+	]}`)
+	assert.Equal(t, "clai/echo", res["model"])
+	assert.Equal(t, "upstream: Echo: second", content(res))
+	assert.Equal(t, 3.0, res["usage"].(map[string]any)["total_tokens"])
+
+	// Other models are forwarded unchanged
+	res = completion(`{"model": "gpt-4o", "messages": [{"role": "user", "content": "hi"}]}`)
+	assert.Equal(t, "gpt-4o: hi", content(res))
+
+	// Streaming
+	resp, body := doRequest(t, "POST", proxy.URL+"/v1/chat/completions", `{"model": "clai/echo", "stream": true, "messages": [{"role": "user", "content": "a b"}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var streamed strings.Builder
+	events := strings.Split(strings.TrimSpace(body), "\n\n")
+	require.Equal(t, "data: [DONE]", events[len(events)-1])
+	for _, event := range events[:len(events)-1] {
+		var chunk ai.StreamChunk
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(event, "data: ")), &chunk))
+		streamed.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	assert.Equal(t, "upstream: Echo: a b", streamed.String())
+	assert.Contains(t, events[len(events)-2], `"finish_reason":"stop"`)
+
+	// Models
+	resp, body = doRequest(t, "GET", proxy.URL+"/v1/models", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"id":"clai/monster"`)
+
+	// Errors
+	resp, body = doRequest(t, "POST", proxy.URL+"/v1/chat/completions", `{"model": "clai/missing", "messages": []}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Contains(t, body, `"message":"unknown workflow \"missing\""`)
+
+	resp, _ = doRequest(t, "POST", proxy.URL+"/v1/chat/completions", `{"model": "clai/broken", "messages": []}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestProxyPool(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ai.Request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":%q}}]}`, req.Model)
+	}))
+	defer upstream.Close()
+
+	pool, err := ai.NewPool(ai.BalanceFailover, &ai.Endpoint{Name: "primary", Client: ai.NewClient(ai.WithURL(upstream.URL), ai.WithModel("primary"))})
+	require.NoError(t, err)
+
+	s, _ := newTestServer(t, func(profile string) (*ai.Client, error) {
+		return ai.NewClient(ai.WithPool(pool), ai.WithModel("primary")), nil
+	})
+	proxy := httptest.NewServer(s.ProxyHandler())
+	defer proxy.Close()
+
+	// The endpoints of a pool use their own models, so other models are an error
+	resp, body := doRequest(t, "POST", proxy.URL+"/v1/chat/completions", `{"model": "gpt-4o", "messages": [{"role": "user", "content": "hi"}]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, body, "endpoint pool")
+
+	resp, body = doRequest(t, "POST", proxy.URL+"/v1/chat/completions", `{"messages": [{"role": "user", "content": "hi"}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Contains(t, body, `"content":"primary"`)
+}
+
+func TestProxyPassesFieldsThrough(t *testing.T) {
+	var received map[string]any
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if received["model"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"message":"no such model"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"1","type":"function","function":{"name":"roll","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`)
+	}))
+	defer upstream.Close()
+
+	s, _ := newTestServer(t, func(profile string) (*ai.Client, error) {
+		return ai.NewClient(ai.WithURL(upstream.URL), ai.WithModel("upstream")), nil
+	})
+	proxy := httptest.NewServer(s.ProxyHandler())
+	defer proxy.Close()
+
+	// Other models are forwarded unchanged, including the fields and content parts
+	// clai doesn't know and the response of the api
+	request := `{"model": "gpt-4o", "temperature": 0.2, "max_tokens": 5, "logit_bias": {"50256": -100},
+		"tools": [{"type": "function", "function": {"name": "roll", "parameters": {"type": "object"}}}],
+		"messages": [
+			{"role": "user", "content": [{"type": "text", "text": "What is this?"}, {"type": "image_url", "image_url": {"url": "https://example.com/map.png", "detail": "low"}}]},
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "1", "type": "function", "function": {"name": "roll", "arguments": "{}"}}]},
+			{"role": "tool", "tool_call_id": "1", "content": "4"}
+		]}`
+	resp, body := doRequest(t, "POST", proxy.URL+"/v1/chat/completions", request)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Contains(t, body, `"tool_calls":[{"id":"1"`)
+	assert.Contains(t, body, `"finish_reason":"tool_calls"`)
+
+	var expected map[string]any
+	require.NoError(t, json.Unmarshal([]byte(request), &expected))
+	assert.Equal(t, expected, received)
+
+	// Errors of the api are passed on as well
+	resp, body = doRequest(t, "POST", proxy.URL+"/v1/chat/completions", `{"model": "missing", "messages": []}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, `{"error":{"message":"no such model"}}`, body)
+
+	// Workflows send the other fields with the rendered messages
+	resp, body = doRequest(t, "POST", proxy.URL+"/v1/chat/completions", `{"model": "clai/echo", "temperature": 0.2, "max_tokens": 5,
+		"stream_options": {"include_usage": true}, "messages": [{"role": "user", "content": "hi"}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, map[string]any{
+		"model":       "upstream",
+		"temperature": 0.2,
+		"max_tokens":  5.0,
+		"messages":    []any{map[string]any{"role": "user", "content": "Echo: hi"}},
+	}, received)
+}
+
+func TestProxyConcurrency(t *testing.T) {
+	s, _ := newTestServer(t, func(profile string) (*ai.Client, error) {
+		return ai.NewClient(ai.WithMock("")), nil
+	})
+	s.Concurrency = 1
+	marker := writeMarkWorkflow(t, s.Dir)
+
+	proxy := httptest.NewServer(s.ProxyHandler())
+	defer proxy.Close()
+
+	// While all slots are taken, the workflow isn't even rendered
+	require.True(t, s.acquire(httptest.NewRequest("GET", "/", nil)))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", proxy.URL+"/v1/chat/completions", strings.NewReader(`{"model": "clai/mark", "messages": []}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	_, err = http.DefaultClient.Do(req)
+	require.Error(t, err)
+	assert.NoFileExists(t, marker)
+
+	s.release()
+	resp, body := doRequest(t, "POST", proxy.URL+"/v1/chat/completions", `{"model": "clai/mark", "messages": []}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.FileExists(t, marker)
+}
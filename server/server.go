@@ -0,0 +1,412 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/executor"
+	"github.com/bigjk/clai/runner"
+	"github.com/bigjk/clai/templating"
+)
+
+// ClientFactory returns the api client for a profile, an empty profile selects the
+// default of the config
+type ClientFactory func(profile string) (*ai.Client, error)
+
+// Server exposes the workflows of a directory as http api:
+//
+//	GET  /workflows             lists the workflows with their declared inputs
+//	POST /workflows/{name}/run  runs a workflow, streams with server sent events if requested
+//	POST /workflows/{name}/dry  renders the messages of a workflow without sending them
+type Server struct {
+	// Dir contains the workflows, the name of a workflow is its file name without the
+	// extension
+	Dir string
+	// WorkingDir is the working directory of the helpers
+	WorkingDir string
+	// Token is the bearer token required for all requests if set
+	Token string
+	// Concurrency is the maximum number of workflows that run at the same time,
+	// further requests wait. Defaults to 4.
+	Concurrency int
+
+	Clients ClientFactory
+	Options []executor.Options
+
+	// Record is called with every finished run if set, e.g. to save it to the history
+	Record func(file string, content []byte, result *runner.Result)
+
+	once    sync.Once
+	sem     chan struct{}
+	mu      sync.Mutex
+	clients map[string]*ai.Client
+}
+
+// WorkflowInfo describes a workflow in the listing
+type WorkflowInfo struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Profile     string             `json:"profile,omitempty"`
+	Inputs      []templating.Input `json:"inputs"`
+}
+
+// RunRequest is the body of the run and dry endpoints. Input is a string or an object
+// that is passed as JSON input.
+type RunRequest struct {
+	Input  json.RawMessage `json:"input"`
+	Seed   int64           `json:"seed"`
+	Stream bool            `json:"stream"`
+}
+
+// Handler returns the http handler of the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /workflows", s.handleList)
+	mux.HandleFunc("POST /workflows/{name}/run", s.handleRun)
+	mux.HandleFunc("POST /workflows/{name}/dry", s.handleDry)
+	return s.authorize(mux)
+}
+
+// authorize checks the bearer token of the requests
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing bearer token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	names, err := s.workflowNames()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	workflows := []WorkflowInfo{}
+	for _, name := range names {
+		workflow, _, err := loadWorkflow(s.workflowFile(name))
+		if err != nil {
+			continue
+		}
+
+		info := WorkflowInfo{
+			Name:        name,
+			Description: workflow.Meta.Description,
+			Profile:     workflow.Meta.Profile,
+			Inputs:      workflow.Meta.Inputs,
+		}
+		if info.Inputs == nil {
+			info.Inputs = []templating.Input{}
+		}
+		workflows = append(workflows, info)
+	}
+
+	writeJSON(w, http.StatusOK, workflows)
+}
+
+func (s *Server) handleDry(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.prepare(w, r)
+	if !ok {
+		return
+	}
+
+	// Rendering runs the helpers, so it is limited like runs
+	if !s.acquire(r) {
+		writeError(w, http.StatusServiceUnavailable, r.Context().Err())
+		return
+	}
+	defer s.release()
+
+	messages, err := req.runner.Render(req.input, req.seed)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"seed": req.seed, "messages": messages})
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.prepare(w, r)
+	if !ok {
+		return
+	}
+
+	if !s.acquire(r) {
+		writeError(w, http.StatusServiceUnavailable, r.Context().Err())
+		return
+	}
+	defer s.release()
+
+	messages, err := req.runner.Render(req.input, req.seed)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	result := &runner.Result{Seed: req.seed, Input: req.input, Messages: messages}
+
+	stream := req.stream || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	flusher, canFlush := w.(http.Flusher)
+	if !stream || !canFlush {
+		if err := req.runner.Send(result); err != nil {
+			s.record(req, result, err)
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		s.record(req, result, nil)
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	err = req.runner.Stream(result, func(delta string) {
+		writeEvent(w, "delta", map[string]string{"content": delta})
+		flusher.Flush()
+	})
+	s.record(req, result, err)
+	if err != nil {
+		writeEvent(w, "error", map[string]string{"error": err.Error()})
+	} else {
+		writeEvent(w, "result", result)
+	}
+	flusher.Flush()
+}
+
+// request is a prepared run of a workflow
+type request struct {
+	file    string
+	content []byte
+	input   string
+	seed    int64
+	stream  bool
+	runner  *runner.Runner
+}
+
+// prepare loads the workflow and decodes the body of a run or dry request. Errors are
+// written to the response.
+func (s *Server) prepare(w http.ResponseWriter, r *http.Request) (*request, bool) {
+	name := r.PathValue("name")
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown workflow %q", name))
+		return nil, false
+	}
+
+	file := s.workflowFile(name)
+	workflow, content, err := loadWorkflow(file)
+	if os.IsNotExist(err) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown workflow %q", name))
+		return nil, false
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	var body RunRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 10<<20)).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("error decoding request: %w", err))
+			return nil, false
+		}
+	}
+
+	input, err := requestInput(body.Input, workflow.Meta.Inputs)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	client, err := s.client(workflow.Meta.Profile)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	seed := body.Seed
+	if seed == 0 {
+		seed = runner.NewSeed()
+	}
+
+	return &request{
+		file:    file,
+		content: content,
+		input:   input,
+		seed:    seed,
+		stream:  body.Stream,
+		runner: &runner.Runner{
+			Workflow:   workflow,
+			Client:     client,
+			WorkingDir: s.WorkingDir,
+			Options:    s.options(client),
+		},
+	}, true
+}
+
+// options returns the executor options of a run with the client
+func (s *Server) options(client *ai.Client) []executor.Options {
+	return append(s.Options[:len(s.Options):len(s.Options)], executor.WithEmbedder(client))
+}
+
+// client returns the client of the profile. Clients are reused, so the circuit
+// breakers of pools keep their state between requests.
+func (s *Server) client(profile string) (*ai.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if client, ok := s.clients[profile]; ok {
+		return client, nil
+	}
+
+	client, err := s.Clients(profile)
+	if err != nil {
+		return nil, err
+	}
+	if s.clients == nil {
+		s.clients = map[string]*ai.Client{}
+	}
+	s.clients[profile] = client
+	return client, nil
+}
+
+// acquire waits for a free slot, it fails if the request is canceled while waiting
+func (s *Server) acquire(r *http.Request) bool {
+	s.once.Do(func() {
+		concurrency := s.Concurrency
+		if concurrency <= 0 {
+			concurrency = 4
+		}
+		s.sem = make(chan struct{}, concurrency)
+	})
+
+	select {
+	case s.sem <- struct{}{}:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (s *Server) release() {
+	<-s.sem
+}
+
+func (s *Server) record(req *request, result *runner.Result, err error) {
+	if s.Record == nil {
+		return
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	s.Record(req.file, req.content, result)
+}
+
+// requestInput converts the input of a request to the workflow input. Strings are
+// used as is, objects are passed as JSON and checked for the required inputs.
+func requestInput(raw json.RawMessage, inputs []templating.Input) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = nil
+	}
+
+	var text string
+	if raw != nil && json.Unmarshal(raw, &text) == nil {
+		return text, nil
+	}
+
+	var fields map[string]any
+	if raw != nil {
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return "", fmt.Errorf("input must be a string or an object")
+		}
+	}
+
+	for _, input := range inputs {
+		if _, ok := fields[input.Name]; input.Required && !ok {
+			return "", fmt.Errorf("missing required input %q", input.Name)
+		}
+	}
+
+	if raw == nil {
+		return "", nil
+	}
+	return string(raw), nil
+}
+
+// workflowNames returns the sorted names of the workflows in the directory
+func (s *Server) workflowNames() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || !templating.IsWorkflowFile(entry.Name()) {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// workflowFile returns the file of the workflow with the name. If there are several,
+// the first of templating.Extensions wins. If there is none the .md file is returned,
+// which fails to load.
+func (s *Server) workflowFile(name string) string {
+	for _, ext := range templating.Extensions {
+		file := filepath.Join(s.Dir, name+ext)
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			return file
+		}
+	}
+	return filepath.Join(s.Dir, name+".md")
+}
+
+func loadWorkflow(file string) (*templating.Workflow, []byte, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	workflow, err := templating.ParseWorkflowFile(file, string(content))
+	if err != nil {
+		return nil, nil, err
+	}
+	return workflow, content, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeEvent(w http.ResponseWriter, event string, value any) {
+	data, _ := json.Marshal(value)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
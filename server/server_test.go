@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, clients ClientFactory) (*Server, *httptest.Server) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	write("monster.md", `---
+description: Generates a monster
+inputs:
+  - name: name
+    required: true
+  - cr
+---
+# CLAI::USER
+Monster {{ .name }} with cr {{ .cr }}`)
+	write("echo.yaml", "messages:\n  - role: user\n    content: \"Echo: {{ .Input }}\"")
+	write("broken.md", "# CLAI::USER\n{{ call .Missing }}")
+	write("notes.txt", "not a workflow")
+
+	s := &Server{Dir: dir, WorkingDir: dir, Token: "secret", Clients: clients}
+	server := httptest.NewServer(s.Handler())
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+// writeMarkWorkflow writes the workflow "mark", which creates the returned marker file
+// when it is rendered
+func writeMarkWorkflow(t *testing.T, dir string) string {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mark.sh"), []byte("#!/bin/sh\ntouch \"$(dirname \"$0\")/marker\"\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mark.md"), []byte(`# CLAI::USER
+{{ call .RunCommand "./mark.sh" }}marked`), 0644))
+	return filepath.Join(dir, "marker")
+}
+
+func doRequest(t *testing.T, method string, url string, body string) (*http.Response, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(data)
+}
+
+func TestServer(t *testing.T) {
+	var recorded []*runner.Result
+	var mu sync.Mutex
+
+	s, server := newTestServer(t, func(profile string) (*ai.Client, error) {
+		return ai.NewClient(ai.WithMock("")), nil
+	})
+	s.Record = func(file string, content []byte, result *runner.Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		recorded = append(recorded, result)
+	}
+
+	// Listing
+	resp, body := doRequest(t, "GET", server.URL+"/workflows", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var workflows []WorkflowInfo
+	require.NoError(t, json.Unmarshal([]byte(body), &workflows))
+	require.Len(t, workflows, 3)
+	assert.Equal(t, "echo", workflows[1].Name)
+	assert.Equal(t, "monster", workflows[2].Name)
+	assert.Equal(t, "Generates a monster", workflows[2].Description)
+	assert.Len(t, workflows[2].Inputs, 2)
+	assert.True(t, workflows[2].Inputs[0].Required)
+
+	// Runs
+	resp, body = doRequest(t, "POST", server.URL+"/workflows/monster/run", `{"input": {"name": "Ghoul", "cr": 2}, "seed": 5}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+	var result runner.Result
+	require.NoError(t, json.Unmarshal([]byte(body), &result))
+	assert.Equal(t, "Monster Ghoul with cr 2", result.Response)
+	assert.Equal(t, int64(5), result.Seed)
+	assert.Len(t, recorded, 1)
+
+	resp, body = doRequest(t, "POST", server.URL+"/workflows/echo/dry", `{"input": "hello"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Contains(t, body, `"content":"Echo: hello"`)
+	assert.Len(t, recorded, 1)
+
+	// Errors
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{method: "POST", path: "/workflows/monster/run", body: `{"input": {"cr": 2}}`, status: http.StatusBadRequest},
+		{method: "POST", path: "/workflows/monster/run", body: `{"input": 5}`, status: http.StatusBadRequest},
+		{method: "POST", path: "/workflows/monster/run", body: `{`, status: http.StatusBadRequest},
+		{method: "POST", path: "/workflows/broken/dry", status: http.StatusBadRequest},
+		{method: "POST", path: "/workflows/missing/run", status: http.StatusNotFound},
+		{method: "POST", path: "/workflows/notes.txt/run", status: http.StatusNotFound},
+		{method: "GET", path: "/workflows/echo/run", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, body := doRequest(t, tt.method, server.URL+tt.path, tt.body)
+			assert.Equal(t, tt.status, resp.StatusCode, body)
+		})
+	}
+
+	// Authorization
+	resp, err := http.Get(server.URL + "/workflows")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestServerStream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ai.Request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.True(t, req.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, word := range strings.Fields(req.Messages[0].Content.(string)) {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", word+" ")
+		}
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":3,\"total_tokens\":6}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer upstream.Close()
+
+	_, server := newTestServer(t, func(profile string) (*ai.Client, error) {
+		return ai.NewClient(ai.WithURL(upstream.URL), ai.WithModel("streamer")), nil
+	})
+
+	resp, body := doRequest(t, "POST", server.URL+"/workflows/echo/run", `{"input": "a b", "stream": true}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := strings.Split(strings.TrimSpace(body), "\n\n")
+	require.Len(t, events, 4)
+	assert.Equal(t, "event: delta\ndata: {\"content\":\"Echo: \"}", events[0])
+	assert.Equal(t, "event: delta\ndata: {\"content\":\"b \"}", events[2])
+
+	data, ok := strings.CutPrefix(events[3], "event: result\ndata: ")
+	require.True(t, ok, events[3])
+
+	var result runner.Result
+	require.NoError(t, json.Unmarshal([]byte(data), &result))
+	assert.Equal(t, "Echo: a b ", result.Raw)
+	assert.Equal(t, 6, result.Usage.TotalTokens)
+}
+
+func TestServerDryConcurrency(t *testing.T) {
+	s, server := newTestServer(t, func(profile string) (*ai.Client, error) {
+		return ai.NewClient(ai.WithMock("")), nil
+	})
+	s.Concurrency = 1
+	marker := writeMarkWorkflow(t, s.Dir)
+
+	// While all slots are taken, dry runs don't render either
+	require.True(t, s.acquire(httptest.NewRequest("GET", "/", nil)))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", server.URL+"/workflows/mark/dry", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	_, err = http.DefaultClient.Do(req)
+	require.Error(t, err)
+	assert.NoFileExists(t, marker)
+
+	s.release()
+	resp, body := doRequest(t, "POST", server.URL+"/workflows/mark/dry", `{}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Contains(t, body, `"content":"marked"`)
+	assert.FileExists(t, marker)
+}
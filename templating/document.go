@@ -0,0 +1,379 @@
+package templating
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bigjk/clai/ai"
+	"gopkg.in/yaml.v3"
+)
+
+// Extensions are the file extensions of workflows. Markdown files use role markers,
+// the others are structured documents with a list of messages.
+var Extensions = []string{".md", ".yaml", ".yml", ".json"}
+
+// IsWorkflowFile checks if the file has the extension of a workflow
+func IsWorkflowFile(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	for _, e := range Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStructured checks if the file is a YAML or JSON workflow
+func IsStructured(file string) bool {
+	return IsWorkflowFile(file) && strings.ToLower(filepath.Ext(file)) != ".md"
+}
+
+// Document is a workflow before it is converted to messages. It is the common form of
+// the markdown and the structured formats, so workflows can be converted between them.
+//
+// In YAML or JSON the parameters of the frontmatter are top-level fields next to the
+// messages:
+//
+//	profile: local
+//	extract: codeblock:yaml
+//	messages:
+//	  - role: system
+//	    content: You are a monster generator.
+//	    cache: true
+//	  - role: user
+//	    content: "{{ .Input }}"
+//	    attachments: [./map.png]
+type Document struct {
+	Meta `yaml:",inline"`
+
+	// Preamble is the content before the first role marker, it is not sent
+	Preamble string            `yaml:"preamble,omitempty"`
+	Messages []DocumentMessage `yaml:"messages"`
+}
+
+// DocumentMessage is a message of a document
+type DocumentMessage struct {
+	Role        string   `yaml:"role"`
+	Content     string   `yaml:"content"`
+	Name        string   `yaml:"name,omitempty"`
+	Cache       bool     `yaml:"cache,omitempty"`
+	Attachments []string `yaml:"attachments,omitempty"`
+
+	// Line is the line of the file the content starts on, if known
+	Line int `yaml:"-"`
+}
+
+// ParseDocument parses a workflow in the format of the file extension
+func ParseDocument(file string, content string) (*Document, error) {
+	if IsStructured(file) {
+		return ParseStructuredDocument(content)
+	}
+	return ParseMarkdownDocument(content)
+}
+
+// ParseMarkdownDocument parses a workflow consisting of an optional YAML frontmatter
+// followed by the messages separated by role markers
+func ParseMarkdownDocument(content string) (*Document, error) {
+	frontmatter, body := SplitFrontmatter(content)
+
+	doc := &Document{}
+	if frontmatter != "" {
+		if err := yaml.Unmarshal([]byte(frontmatter), &doc.Meta); err != nil {
+			return nil, fmt.Errorf("error parsing workflow frontmatter: %w", err)
+		}
+	}
+
+	offset := BodyOffset(content, body)
+	for _, section := range ParseSections(body) {
+		if section.Preamble {
+			doc.Preamble = section.Raw
+			continue
+		}
+
+		msg, err := section.Message()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing workflow: line %d: %w", section.Line+offset, err)
+		}
+		doc.Messages = append(doc.Messages, DocumentMessage{
+			Role:        msg.Role,
+			Content:     section.Raw,
+			Name:        msg.Name,
+			Cache:       msg.Cache,
+			Attachments: msg.Attachments,
+			Line:        section.ContentLine + offset,
+		})
+	}
+	return doc, nil
+}
+
+// ParseStructuredDocument parses a workflow in YAML or JSON
+func ParseStructuredDocument(content string) (*Document, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		return nil, fmt.Errorf("error parsing workflow: %w", err)
+	}
+
+	doc := &Document{}
+	if err := node.Decode(doc); err != nil {
+		return nil, fmt.Errorf("error parsing workflow: %w", err)
+	}
+
+	for i, line := range contentLines(&node) {
+		if i < len(doc.Messages) {
+			doc.Messages[i].Line = line
+		}
+	}
+	for i, msg := range doc.Messages {
+		if msg.Role == "" {
+			return nil, fmt.Errorf("error parsing workflow: message %d has no role", i+1)
+		}
+		doc.Messages[i].Role = strings.ToLower(msg.Role)
+	}
+	return doc, nil
+}
+
+// contentLines returns the lines the contents of the messages start on
+func contentLines(node *yaml.Node) []int {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var lines []int
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "messages" || node.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, msg := range node.Content[i+1].Content {
+			line := msg.Line
+			for j := 0; msg.Kind == yaml.MappingNode && j+1 < len(msg.Content); j += 2 {
+				if value := msg.Content[j+1]; msg.Content[j].Value == "content" {
+					line = value.Line
+					// Block scalars start on the line after the indicator
+					if value.Style == yaml.LiteralStyle || value.Style == yaml.FoldedStyle {
+						line++
+					}
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Workflow converts the document to the messages that are rendered. The content is
+// trimmed and empty messages are dropped in all formats.
+func (d *Document) Workflow() *Workflow {
+	workflow := &Workflow{Meta: d.Meta}
+	for _, msg := range d.Messages {
+		content := strings.TrimSpace(msg.Content)
+		if msg.Role == "" || content == "" {
+			continue
+		}
+
+		workflow.Messages = append(workflow.Messages, ai.Message{
+			Role:        msg.Role,
+			Content:     content,
+			Name:        msg.Name,
+			Cache:       msg.Cache,
+			Attachments: msg.Attachments,
+		})
+		workflow.Lines = append(workflow.Lines, msg.Line)
+	}
+	return workflow
+}
+
+// YAML writes the document in the structured format
+func (d *Document) YAML() (string, error) {
+	data, err := marshalYAML(d)
+	if err != nil {
+		return "", err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &node); err != nil {
+		return "", err
+	}
+	root := node.Content[0]
+	keepLeadingBreak(mappingValue(root, "preamble"), d.Preamble)
+	if messages := mappingValue(root, "messages"); messages != nil {
+		for i, msg := range messages.Content {
+			keepLeadingBreak(mappingValue(msg, "content"), d.Messages[i].Content)
+		}
+	}
+	return marshalYAML(&node)
+}
+
+// JSON writes the document in the structured format as JSON. The fields are the same
+// as in YAML.
+func (d *Document) JSON() (string, error) {
+	data, err := d.YAML()
+	if err != nil {
+		return "", err
+	}
+
+	// Going through YAML keeps the field names of the yaml tags
+	var value any
+	if err := yaml.Unmarshal([]byte(data), &value); err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Markdown writes the document with frontmatter and role markers. The contents are
+// written as they are, only lines that would be read as marker are escaped. Parsing
+// the result gives the same document.
+func (d *Document) Markdown() (string, error) {
+	var out strings.Builder
+
+	frontmatter, err := marshalYAML(d.Meta)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case frontmatter != "{}\n":
+		out.WriteString("---\n")
+		out.WriteString(frontmatter)
+		out.WriteString("---\n")
+	case strings.TrimSpace(strings.SplitN(d.Preamble, "\n", 2)[0]) == "---":
+		// An empty frontmatter keeps the preamble from being read as one
+		out.WriteString("---\n---\n")
+	}
+
+	if d.Preamble != "" {
+		preamble, err := escapeMarkers(d.Preamble)
+		if err != nil {
+			return "", fmt.Errorf("preamble: %w", err)
+		}
+		out.WriteString(preamble + "\n")
+	}
+
+	for i, msg := range d.Messages {
+		marker, err := markerLine(msg)
+		if err != nil {
+			return "", fmt.Errorf("message %d: %w", i+1, err)
+		}
+		content, err := escapeMarkers(msg.Content)
+		if err != nil {
+			return "", fmt.Errorf("message %d: %w", i+1, err)
+		}
+
+		out.WriteString(marker + "\n")
+		if content != "" {
+			out.WriteString(content + "\n")
+		}
+	}
+
+	return out.String(), nil
+}
+
+// markerLine returns the role marker of the message with its attributes
+func markerLine(msg DocumentMessage) (string, error) {
+	if msg.Role == "" || strings.ContainsFunc(msg.Role, isSpace) {
+		return "", fmt.Errorf("invalid role %q", msg.Role)
+	}
+
+	fields := []string{MarkerPrefix + strings.ToUpper(msg.Role)}
+	if msg.Name != "" {
+		fields = append(fields, "name="+quoteAttr(msg.Name))
+	}
+	if msg.Cache {
+		fields = append(fields, "cache=true")
+	}
+	if len(msg.Attachments) > 0 {
+		for _, file := range msg.Attachments {
+			if strings.Contains(file, ",") || strings.TrimSpace(file) != file || file == "" {
+				return "", fmt.Errorf("attachment %q can't be written as marker attribute", file)
+			}
+		}
+		fields = append(fields, "attach="+quoteAttr(strings.Join(msg.Attachments, ",")))
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// quoteAttr quotes attribute values that contain spaces or quotes
+func quoteAttr(value string) string {
+	if strings.ContainsFunc(value, isSpace) || strings.ContainsRune(value, '"') {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// escapeMarkers adds a backslash to the lines outside of code fences that would be
+// read as role markers. It fails if the content ends inside of a code fence, as the
+// fence would hide the following markers.
+func escapeMarkers(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case fence != "":
+			if isFenceEnd(trimmed, fence) {
+				fence = ""
+			}
+		case fenceMarker(trimmed) != "":
+			fence = fenceMarker(trimmed)
+		case strings.HasPrefix(trimmed, MarkerPrefix) || isEscapedMarker(trimmed):
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			lines[i] = indent + `\` + line[len(indent):]
+		}
+	}
+
+	if fence != "" {
+		return "", fmt.Errorf("unclosed code fence %s", fence)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// marshalYAML encodes the value with an indentation of two spaces
+func marshalYAML(value any) (string, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(value); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// keepLeadingBreak sets the content of a scalar node and double quotes it if it starts
+// with a line break, which the encoder drops in block scalars
+func keepLeadingBreak(node *yaml.Node, content string) {
+	if node != nil && strings.HasPrefix(content, "\n") {
+		node.Value = content
+		node.Style = yaml.DoubleQuotedStyle
+	}
+}
+
+// mappingValue returns the value of the key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
@@ -0,0 +1,207 @@
+package templating
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/postprocess"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const markdownDocument = `---
+extract: codeblock:yaml
+profile: local
+inputs:
+  - name: name
+    required: true
+  - cr
+---
+Notes for maintainers.
+
+# CLAI::SYSTEM cache=true
+Workflows look like:
+` + "```md" + `
+# CLAI::USER
+{{ .Input }}
+` + "```" + `
+\# CLAI::USER
+\\# CLAI::USER
+
+# CLAI::USER name="dungeon master" attach=map.png,cave.png
+{{ .name }}
+
+# CLAI::ASSISTANT
+`
+
+func TestDocumentRoundTrip(t *testing.T) {
+	doc, err := ParseMarkdownDocument(markdownDocument)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Notes for maintainers.\n", doc.Preamble)
+	assert.Equal(t, []DocumentMessage{
+		{Role: "system", Content: "Workflows look like:\n```md\n# CLAI::USER\n{{ .Input }}\n```\n# CLAI::USER\n\\# CLAI::USER\n", Cache: true, Line: 12},
+		{Role: "user", Content: "{{ .name }}\n", Name: "dungeon master", Attachments: []string{"map.png", "cave.png"}, Line: 21},
+		{Role: "assistant", Line: 24},
+	}, doc.Messages)
+
+	markdown, err := doc.Markdown()
+	require.NoError(t, err)
+	assert.Equal(t, markdownDocument, markdown)
+
+	yaml, err := doc.YAML()
+	require.NoError(t, err)
+
+	structured, err := ParseStructuredDocument(yaml)
+	require.NoError(t, err)
+	markdown, err = structured.Markdown()
+	require.NoError(t, err)
+	assert.Equal(t, markdownDocument, markdown)
+
+	data, err := doc.JSON()
+	require.NoError(t, err)
+	assert.True(t, json.Valid([]byte(data)), data)
+	assert.Contains(t, data, `"name": "dungeon master"`)
+
+	structured, err = ParseStructuredDocument(data)
+	require.NoError(t, err)
+	markdown, err = structured.Markdown()
+	require.NoError(t, err)
+	assert.Equal(t, markdownDocument, markdown)
+}
+
+func TestDocumentRoundTripKeepsWhitespace(t *testing.T) {
+	doc := &Document{
+		Preamble: "  Indented notes.\n\n",
+		Messages: []DocumentMessage{
+			{Role: "system", Content: "    indented code\n\tand a tab\n\n\n"},
+			{Role: "user", Content: "\n\n  {{ .Input }}\n"},
+			{Role: "assistant", Content: "  trailing\n\n"},
+		},
+	}
+
+	markdown, err := doc.Markdown()
+	require.NoError(t, err)
+
+	parsed, err := ParseMarkdownDocument(markdown)
+	require.NoError(t, err)
+	assert.Equal(t, doc.Preamble, parsed.Preamble)
+	for i, msg := range parsed.Messages {
+		assert.Equal(t, doc.Messages[i].Content, msg.Content)
+	}
+
+	yaml, err := parsed.YAML()
+	require.NoError(t, err)
+	structured, err := ParseStructuredDocument(yaml)
+	require.NoError(t, err)
+	again, err := structured.Markdown()
+	require.NoError(t, err)
+	assert.Equal(t, markdown, again)
+
+	data, err := parsed.JSON()
+	require.NoError(t, err)
+	structured, err = ParseStructuredDocument(data)
+	require.NoError(t, err)
+	again, err = structured.Markdown()
+	require.NoError(t, err)
+	assert.Equal(t, markdown, again)
+
+	yaml, err = doc.YAML()
+	require.NoError(t, err)
+	structured, err = ParseStructuredDocument(yaml)
+	require.NoError(t, err)
+	markdown, err = structured.Markdown()
+	require.NoError(t, err)
+	parsed, err = ParseMarkdownDocument(markdown)
+	require.NoError(t, err)
+	again, err = parsed.YAML()
+	require.NoError(t, err)
+	assert.Equal(t, yaml, again)
+}
+
+func TestParseStructuredDocument(t *testing.T) {
+	yamlDoc := `profile: local
+extract: json
+messages:
+  - role: SYSTEM
+    content: You are a monster generator.
+  - role: user
+    content: |
+      Hi
+      {{ .Input }}
+    attachments: [map.png]
+  - role: assistant
+    content: ""
+`
+	jsonDoc := `{
+  "profile": "local",
+  "extract": "json",
+  "messages": [
+    {"role": "SYSTEM", "content": "You are a monster generator."},
+    {"role": "user", "content": "Hi\n{{ .Input }}\n", "attachments": ["map.png"]},
+    {"role": "assistant", "content": ""}
+  ]
+}`
+
+	tests := []struct {
+		file    string
+		content string
+		lines   []int
+	}{
+		{file: "monsters.yaml", content: yamlDoc, lines: []int{5, 8}},
+		{file: "monsters.json", content: jsonDoc, lines: []int{5, 6}},
+	}
+
+	for _, tt := range tests {
+		workflow, err := ParseWorkflowFile(tt.file, tt.content)
+		require.NoError(t, err, tt.file)
+
+		assert.Equal(t, "local", workflow.Meta.Profile, tt.file)
+		assert.Equal(t, postprocess.Config{Extract: "json"}, workflow.Meta.Config, tt.file)
+		assert.Equal(t, []ai.Message{
+			{Role: "system", Content: "You are a monster generator."},
+			{Role: "user", Content: "Hi\n{{ .Input }}", Attachments: []string{"map.png"}},
+		}, workflow.Messages, tt.file)
+		assert.Equal(t, tt.lines, workflow.Lines, tt.file)
+	}
+
+	_, err := ParseWorkflowFile("broken.yaml", "messages:\n  - content: Hi")
+	assert.EqualError(t, err, "error parsing workflow: message 1 has no role")
+
+	_, err = ParseWorkflowFile("broken.yaml", "messages: [")
+	assert.Error(t, err)
+}
+
+func TestMarkdownErrors(t *testing.T) {
+	tests := []struct {
+		doc      Document
+		expected string
+	}{
+		{
+			doc:      Document{Messages: []DocumentMessage{{Role: "user", Content: "```\n# CLAI::USER"}}},
+			expected: "message 1: unclosed code fence ```",
+		},
+		{
+			doc:      Document{Messages: []DocumentMessage{{Content: "Hi"}}},
+			expected: `message 1: invalid role ""`,
+		},
+		{
+			doc:      Document{Messages: []DocumentMessage{{Role: "user", Attachments: []string{"a,b.png"}}}},
+			expected: `message 1: attachment "a,b.png" can't be written as marker attribute`,
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := tt.doc.Markdown()
+		assert.EqualError(t, err, tt.expected)
+	}
+}
+
+func TestIsWorkflowFile(t *testing.T) {
+	assert.True(t, IsWorkflowFile("a/monsters.md"))
+	assert.True(t, IsWorkflowFile("monsters.YAML"))
+	assert.False(t, IsWorkflowFile("monsters.txt"))
+	assert.False(t, IsStructured("monsters.md"))
+	assert.True(t, IsStructured("monsters.json"))
+}
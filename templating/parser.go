@@ -1,47 +1,226 @@
 package templating
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/bigjk/clai/ai"
 )
 
-// ParseTemplate takes a template string and returns a slice of ai.Message
-func ParseTemplate(template string) []ai.Message {
-	var messages []ai.Message
-	var currentRole string
-	var currentContent []string
+// MarkerPrefix starts the lines that switch the role of the following content
+const MarkerPrefix = "# CLAI::"
+
+// Section is a message of a template before it is rendered
+type Section struct {
+	// Role is the lower case role of the marker
+	Role string
+	// Attrs are the attributes of the marker like "name=dm cache=true". Attributes
+	// without value are "true".
+	Attrs map[string]string
+	// Content is the trimmed content of the section
+	Content string
+	// Raw is the content as it is, without the line break before the next marker
+	// and the one at the end of the template
+	Raw string
+	// Line is the line of the marker, starting at 1
+	Line int
+	// ContentLine is the line the trimmed content starts on
+	ContentLine int
+	// Preamble is set for the content before the first marker, which has no role
+	Preamble bool
+}
 
-	lines := strings.Split(template, "\n")
+// ParseSections splits a template into sections at the role markers. Markers inside
+// fenced code blocks are content, as are markers escaped with a backslash like
+// "\# CLAI::USER", which loses one backslash. The content before the first marker is
+// returned as preamble section if it isn't empty.
+func ParseSections(template string) []Section {
+	var sections []Section
+	current := &Section{Line: 1, Preamble: true}
+	var content []string
+	fence := ""
+
+	flush := func() {
+		raw := strings.Join(content, "\n")
+		trimmed := strings.TrimSpace(raw)
+		current.Content = trimmed
+		current.Raw = raw
+		current.ContentLine = current.Line
+		if !current.Preamble {
+			current.ContentLine++
+		}
+		if trimmed != "" {
+			current.ContentLine += strings.Count(raw[:strings.Index(raw, trimmed)], "\n")
+		}
+		if !current.Preamble || trimmed != "" {
+			sections = append(sections, *current)
+		}
+		content = nil
+	}
 
-	for _, line := range lines {
+	for i, line := range strings.Split(strings.TrimSuffix(template, "\n"), "\n") {
 		trimmedLine := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmedLine, "# CLAI::") {
-			// If we have a previous role and content, add it to messages
-			if currentRole != "" && len(currentContent) > 0 {
-				messages = append(messages, ai.Message{
-					Role:    strings.ToLower(currentRole),
-					Content: strings.TrimSpace(strings.Join(currentContent, "\n")),
-				})
-				currentContent = nil
+
+		if fence != "" {
+			if isFenceEnd(trimmedLine, fence) {
+				fence = ""
+			}
+			content = append(content, line)
+			continue
+		}
+		if marker := fenceMarker(trimmedLine); marker != "" {
+			fence = marker
+			content = append(content, line)
+			continue
+		}
+
+		if isEscapedMarker(trimmedLine) {
+			content = append(content, strings.Replace(line, `\`, "", 1))
+			continue
+		}
+
+		if marker, ok := strings.CutPrefix(trimmedLine, MarkerPrefix); ok {
+			flush()
+			role, attrs := parseMarker(marker)
+			current = &Section{Role: role, Attrs: attrs, Line: i + 1}
+			continue
+		}
+
+		content = append(content, line)
+	}
+	flush()
+
+	return sections
+}
+
+// Message converts the section to a message. The attributes "name" and "cache" set
+// the fields of the same name, "attach" the comma separated attachments. Other
+// attributes are an error.
+func (s Section) Message() (ai.Message, error) {
+	keys := make([]string, 0, len(s.Attrs))
+	for key := range s.Attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	msg := ai.Message{Role: s.Role, Content: s.Content}
+	for _, key := range keys {
+		value := s.Attrs[key]
+		switch key {
+		case "name":
+			msg.Name = value
+		case "cache":
+			cache, err := strconv.ParseBool(value)
+			if err != nil {
+				return msg, fmt.Errorf("invalid value %q of attribute cache", value)
+			}
+			msg.Cache = cache
+		case "attach":
+			for _, file := range strings.Split(value, ",") {
+				if file = strings.TrimSpace(file); file != "" {
+					msg.Attachments = append(msg.Attachments, file)
+				}
 			}
-			// Extract new role
-			currentRole = strings.TrimPrefix(trimmedLine, "# CLAI::")
-		} else {
-			// Add line to current content if we have a role
-			if currentRole != "" {
-				currentContent = append(currentContent, line)
+		default:
+			return msg, fmt.Errorf("unknown attribute %q", key)
+		}
+	}
+	return msg, nil
+}
+
+// ParseTemplate takes a template string and returns a slice of ai.Message. Empty
+// messages are left out, as is the content before the first marker, which is never
+// sent like the preamble of workflows. Invalid marker attributes are an error.
+func ParseTemplate(template string) ([]ai.Message, error) {
+	var messages []ai.Message
+	for _, section := range ParseSections(template) {
+		if section.Preamble || section.Role == "" || section.Content == "" {
+			continue
+		}
+		msg, err := section.Message()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing template: line %d: %w", section.Line, err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// parseMarker splits the text after the marker prefix into the role and the
+// attributes. Values can be quoted like name="dungeon master".
+func parseMarker(marker string) (string, map[string]string) {
+	fields := splitFields(marker)
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	var attrs map[string]string
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			value = "true"
+		} else if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		if attrs == nil {
+			attrs = map[string]string{}
+		}
+		attrs[key] = value
+	}
+	return strings.ToLower(fields[0]), attrs
+}
+
+// splitFields splits at spaces outside of double quotes
+func splitFields(s string) []string {
+	var fields []string
+	var field strings.Builder
+	quoted, escaped := false, false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && unicode.IsSpace(r):
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
 			}
+			continue
 		}
+		field.WriteRune(r)
 	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
 
-	// Add the last message if there is one
-	if currentRole != "" && len(currentContent) > 0 {
-		messages = append(messages, ai.Message{
-			Role:    strings.ToLower(currentRole),
-			Content: strings.TrimSpace(strings.Join(currentContent, "\n")),
-		})
+// fenceMarker returns the opening ``` or ~~~ sequence of a code fence line, or an
+// empty string if the line doesn't open a fence
+func fenceMarker(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n]
+		}
 	}
+	return ""
+}
+
+// isFenceEnd checks if the line closes the fence, which needs the same character at
+// least as often and nothing else
+func isFenceEnd(line string, fence string) bool {
+	return len(line) >= len(fence) && strings.Trim(line, fence[:1]) == ""
+}
 
-	return messages
+// isEscapedMarker checks if the trimmed line is a marker prefixed with backslashes
+func isEscapedMarker(line string) bool {
+	return strings.HasPrefix(line, `\`) && strings.HasPrefix(strings.TrimLeft(line, `\`), MarkerPrefix)
 }
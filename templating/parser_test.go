@@ -12,6 +12,7 @@ func TestParseTemplate(t *testing.T) {
 		name     string
 		template string
 		want     []ai.Message
+		wantErr  bool
 	}{
 		{
 			name: "simple template",
@@ -77,12 +78,69 @@ Message`,
 				{Role: "user", Content: "Multi\nLine\nMessage"},
 			},
 		},
+
+		{
+			name:     "markers in code fences",
+			template: "# CLAI::SYSTEM\nWrite workflows like:\n```md\n# CLAI::USER\n{{ .Input }}\n```\n~~~~\n# CLAI::ASSISTANT\n```\n~~~~\n# CLAI::USER\nHi",
+			want: []ai.Message{
+				{Role: "system", Content: "Write workflows like:\n```md\n# CLAI::USER\n{{ .Input }}\n```\n~~~~\n# CLAI::ASSISTANT\n```\n~~~~"},
+				{Role: "user", Content: "Hi"},
+			},
+		},
+		{
+			name:     "escaped marker",
+			template: "# CLAI::USER\nStart messages with\n  \\# CLAI::USER\nlike this",
+			want: []ai.Message{
+				{Role: "user", Content: "Start messages with\n  # CLAI::USER\nlike this"},
+			},
+		},
+		{
+			name:     "attributes",
+			template: "# CLAI::USER name=dm cache\nHi\n# CLAI::ASSISTANT name=\"Old Bob\" cache=false\nHello",
+			want: []ai.Message{
+				{Role: "user", Content: "Hi", Name: "dm", Cache: true},
+				{Role: "assistant", Content: "Hello", Name: "Old Bob"},
+			},
+		},
+		{
+			name:     "preamble is not sent",
+			template: "Notes for humans\n\n# CLAI::USER\nHi",
+			want: []ai.Message{
+				{Role: "user", Content: "Hi"},
+			},
+		},
+		{
+			name:     "invalid attribute",
+			template: "# CLAI::SYSTEM\nBe nice\n# CLAI::USER cache=yes\nHi",
+			wantErr:  true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ParseTemplate(tt.template)
+			got, err := ParseTemplate(tt.template)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
+
+func TestParseSections(t *testing.T) {
+	sections := ParseSections("Notes for humans\n\n# CLAI::SYSTEM\n\n  You are helpful.\n# CLAI::user cache=yes\n\n# CLAI::USER\nHi")
+
+	assert.Equal(t, []Section{
+		{Content: "Notes for humans", Raw: "Notes for humans\n", Line: 1, ContentLine: 1, Preamble: true},
+		{Role: "system", Content: "You are helpful.", Raw: "\n  You are helpful.", Line: 3, ContentLine: 5},
+		{Role: "user", Attrs: map[string]string{"cache": "yes"}, Line: 6, ContentLine: 7},
+		{Role: "user", Content: "Hi", Raw: "Hi", Line: 8, ContentLine: 9},
+	}, sections)
+
+	_, err := sections[2].Message()
+	assert.EqualError(t, err, `invalid value "yes" of attribute cache`)
+
+	assert.Empty(t, ParseSections("\n\n"))
+}
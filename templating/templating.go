@@ -2,9 +2,11 @@ package templating
 
 import (
 	"bytes"
-	"html/template"
+	"text/template"
 )
 
+// ExecuteTemplate renders the template with the data. The output is a prompt and
+// not HTML, so it uses text/template and inserted values aren't escaped.
 func ExecuteTemplate(str string, data any) (string, error) {
 	tmpl, err := template.New("template").Parse(str)
 	if err != nil {
@@ -0,0 +1,48 @@
+package templating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		data     any
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "field",
+			template: "Hello {{ .Name }}",
+			data:     map[string]any{"Name": "World"},
+			want:     "Hello World",
+		},
+		{
+			name:     "not escaped",
+			template: "{{ .Input }}",
+			data:     map[string]any{"Input": `<b>Tom & Jerry's "cave"</b>`},
+			want:     `<b>Tom & Jerry's "cave"</b>`,
+		},
+		{
+			name:     "syntax error",
+			template: "{{ .Input ",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExecuteTemplate(tt.template, tt.data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package templating
+
+import (
+	"strings"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/postprocess"
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow is a parsed workflow file
+type Workflow struct {
+	Meta     Meta
+	Messages []ai.Message
+	// Lines are the lines of the workflow file the content of the messages starts on
+	Lines []int
+}
+
+// Meta is the optional YAML frontmatter of a workflow
+type Meta struct {
+	postprocess.Config `yaml:",inline"`
+
+	// Profile is the profile of the config the workflow should be sent with
+	Profile string `yaml:"profile,omitempty"`
+
+	// Description is a short summary of what the workflow does
+	Description string `yaml:"description,omitempty"`
+
+	// Inputs are the fields of the JSON input the workflow uses
+	Inputs []Input `yaml:"inputs,omitempty"`
+}
+
+// Input is a declared input of a workflow. In the frontmatter it is either a map or
+// just the name.
+type Input struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty" json:"required"`
+}
+
+// UnmarshalYAML accepts the name of the input as shorthand
+func (i *Input) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*i = Input{Name: node.Value}
+		return nil
+	}
+
+	type plain Input
+	return node.Decode((*plain)(i))
+}
+
+// MarshalYAML writes inputs with just a name as shorthand
+func (i Input) MarshalYAML() (any, error) {
+	if i.Description == "" && !i.Required {
+		return i.Name, nil
+	}
+
+	type plain Input
+	return plain(i), nil
+}
+
+// ParseWorkflow parses a workflow file consisting of an optional YAML frontmatter
+// followed by the messages. Like ParseTemplate it drops empty messages and the
+// content before the first marker and fails on invalid marker attributes.
+func ParseWorkflow(content string) (*Workflow, error) {
+	doc, err := ParseMarkdownDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Workflow(), nil
+}
+
+// ParseWorkflowFile parses a workflow in the format of the file extension, markdown
+// for .md and unknown extensions, else YAML or JSON
+func ParseWorkflowFile(file string, content string) (*Workflow, error) {
+	doc, err := ParseDocument(file, content)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Workflow(), nil
+}
+
+// BodyOffset returns the number of lines before the body returned by SplitFrontmatter
+func BodyOffset(content string, body string) int {
+	return strings.Count(content, "\n") - strings.Count(body, "\n")
+}
+
+// SplitFrontmatter splits content into the YAML frontmatter enclosed in "---" lines
+// and the remaining content
+func SplitFrontmatter(content string) (string, string) {
+	content = strings.TrimPrefix(content, "\ufeff")
+
+	lines := strings.Split(content, "\n")
+	if len(lines) < 2 || strings.TrimSpace(lines[0]) != "---" {
+		return "", content
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			return strings.Join(lines[1:i], "\n"), strings.Join(lines[i+1:], "\n")
+		}
+	}
+
+	return "", content
+}
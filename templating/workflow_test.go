@@ -0,0 +1,52 @@
+package templating
+
+import (
+	"testing"
+
+	"github.com/bigjk/clai/ai"
+	"github.com/bigjk/clai/postprocess"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkflow(t *testing.T) {
+	workflow, err := ParseWorkflow(`---
+extract: codeblock:yaml
+trim: true
+save_raw: true
+profile: local
+description: Generates monsters
+inputs:
+  - name: name
+    description: Name of the monster
+    required: true
+  - cr
+---
+# CLAI::SYSTEM
+You are a monster generator.
+
+# CLAI::USER
+{{ .Input }}`)
+	require.NoError(t, err)
+
+	assert.Equal(t, postprocess.Config{Extract: "codeblock:yaml", Trim: true, SaveRaw: true}, workflow.Meta.Config)
+	assert.Equal(t, "local", workflow.Meta.Profile)
+	assert.Equal(t, "Generates monsters", workflow.Meta.Description)
+	assert.Equal(t, []Input{{Name: "name", Description: "Name of the monster", Required: true}, {Name: "cr"}}, workflow.Meta.Inputs)
+	assert.Equal(t, []ai.Message{
+		{Role: "system", Content: "You are a monster generator."},
+		{Role: "user", Content: "{{ .Input }}"},
+	}, workflow.Messages)
+	assert.Equal(t, []int{14, 17}, workflow.Lines)
+
+	workflow, err = ParseWorkflow("# CLAI::USER\n---\nnot frontmatter\n---")
+	require.NoError(t, err)
+	assert.True(t, workflow.Meta.IsZero())
+	assert.Equal(t, "---\nnot frontmatter\n---", workflow.Messages[0].Content)
+
+	_, err = ParseWorkflow("---\nextract: [\n---\n# CLAI::USER\nHi")
+	assert.Error(t, err)
+
+	_, err = ParseWorkflow("---\nprofile: x\n---\n# CLAI::USER colour=red\nHi")
+	assert.EqualError(t, err, `error parsing workflow: line 4: unknown attribute "colour"`)
+}